@@ -0,0 +1,121 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"project/auth"
+	"project/config"
+	"project/database"
+	"project/models"
+)
+
+var (
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrUsernameTaken      = errors.New("username already exists")
+	ErrEmailTaken         = errors.New("email already exists")
+	ErrDeletionNotPending = errors.New("account deletion is not scheduled")
+)
+
+// UpdateProfile меняет имя пользователя, адрес почты, базовую валюту и часовой пояс.
+// Новый адрес требует повторного подтверждения; возвращает true, если адрес изменился
+func UpdateProfile(user *models.User, username, email, baseCurrency, timezone string) (bool, error) {
+	if username != "" && username != user.Username {
+		taken, err := exists("username = ? AND id <> ?", username, user.ID)
+		if err != nil {
+			return false, err
+		}
+		if taken {
+			return false, ErrUsernameTaken
+		}
+		user.Username = username
+	}
+
+	emailChanged := email != "" && email != user.Email
+	if emailChanged {
+		taken, err := exists("email = ? AND id <> ?", email, user.ID)
+		if err != nil {
+			return false, err
+		}
+		if taken {
+			return false, ErrEmailTaken
+		}
+		user.Email = email
+		user.EmailVerified = false
+		user.VerificationSentAt = nil
+	}
+
+	if baseCurrency != "" {
+		user.BaseCurrency = baseCurrency
+	}
+	if timezone != "" {
+		user.Timezone = timezone
+	}
+
+	err := database.DB.Model(user).
+		Select("username", "email", "email_verified", "verification_sent_at", "base_currency", "timezone").
+		Updates(user).Error
+	return emailChanged, err
+}
+
+// ChangePassword проверяет текущий пароль, устанавливает новый и завершает
+// все сессии пользователя, кроме текущей
+func ChangePassword(user models.User, currentPassword, newPassword string, currentSessionId uint) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return ErrInvalidPassword
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		return err
+	}
+	_, err = auth.RevokeOtherSessions(user.ID, currentSessionId)
+	return err
+}
+
+// ScheduleDeletion назначает удаление аккаунта по истечении льготного периода,
+// завершает все сессии и отзывает персональные токены
+func ScheduleDeletion(user models.User, password string) (time.Time, error) {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return time.Time{}, ErrInvalidPassword
+	}
+
+	now := time.Now()
+	deletionAt := now.Add(config.GetConfig().AccountDeletion.GracePeriod)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("deletion_scheduled_at", deletionAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.APIToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return deletionAt, auth.RevokeAllSessions(user.ID)
+}
+
+// CancelDeletion отменяет назначенное удаление аккаунта
+func CancelDeletion(userId uint) error {
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userId).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotPending
+	}
+	return nil
+}
+
+func exists(query string, args ...interface{}) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.User{}).Where(query, args...).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,127 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"project/auth"
+	"project/config"
+	"project/database"
+	"project/models"
+	"project/oidc"
+)
+
+var (
+	ErrOIDCEmailRequired = errors.New("identity provider did not return an email")
+	// Аккаунт с таким email уже есть, но провайдер не подтвердил владение адресом
+	ErrOIDCEmailNotVerified = errors.New("identity provider email is not verified")
+)
+
+// FindOrCreateOIDCUser возвращает пользователя, связанного с внешней учётной записью.
+// Если связи ещё нет, она создаётся с аккаунтом, имеющим тот же подтверждённый
+// провайдером email, либо с новым аккаунтом
+func FindOrCreateOIDCUser(provider string, claims *oidc.IDTokenClaims) (*models.User, error) {
+	var user models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Preload("User").Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			user = identity.User
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if claims.Email == "" {
+			return ErrOIDCEmailRequired
+		}
+		// Провайдер может вернуть адрес в другом регистре, чем при регистрации
+		err = tx.Where("LOWER(email) = LOWER(?)", claims.Email).First(&user).Error
+		switch {
+		case err == nil:
+			if !claims.EmailVerified {
+				return ErrOIDCEmailNotVerified
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := createOIDCUser(tx, &user, claims); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListIdentities возвращает внешние учётные записи, связанные с пользователем
+func ListIdentities(userId uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := database.DB.Where("user_id = ?", userId).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+func createOIDCUser(tx *gorm.DB, user *models.User, claims *oidc.IDTokenClaims) error {
+	username, err := uniqueUsername(tx, usernameCandidate(claims))
+	if err != nil {
+		return err
+	}
+
+	// Пароль неизвестен никому; при желании пользователь задаст его через сброс пароля
+	randomPassword, err := auth.RandomToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	*user = models.User{
+		Username:      username,
+		Email:         claims.Email,
+		Password:      string(hashedPassword),
+		EmailVerified: claims.EmailVerified,
+		Role:          models.RoleUser,
+		BaseCurrency:  config.GetConfig().Currency.Default,
+	}
+	return tx.Create(user).Error
+}
+
+func usernameCandidate(claims *oidc.IDTokenClaims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if local, _, ok := strings.Cut(claims.Email, "@"); ok && local != "" {
+		return local
+	}
+	return "user"
+}
+
+func uniqueUsername(tx *gorm.DB, base string) (string, error) {
+	candidate := base
+	for i := 1; i <= 100; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", errors.New("could not pick a unique username")
+}
@@ -0,0 +1,168 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/config"
+	"project/database"
+	"project/households"
+	"project/logging"
+	"project/models"
+	"project/notifications"
+)
+
+// Период проверки аккаунтов, у которых истёк льготный период удаления
+const purgeInterval = time.Hour
+
+// StartPurger запускает фоновое окончательное удаление аккаунтов
+func StartPurger() {
+	go func() {
+		purgeDueAccounts()
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purgeDueAccounts()
+		}
+	}()
+}
+
+func purgeDueAccounts() {
+	var userIds []uint
+	err := database.DB.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Pluck("id", &userIds).Error
+	if err != nil {
+		logging.Logger.Error("Failed to find accounts to purge", zap.Error(err))
+		return
+	}
+
+	for _, userId := range userIds {
+		if err := purgeUser(userId); err != nil {
+			logging.Logger.Error("Failed to purge account", zap.Uint("user_id", userId), zap.Error(err))
+			continue
+		}
+		logging.Logger.Info("Account purged", zap.Uint("user_id", userId))
+	}
+}
+
+func purgeUser(userId uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// Удаление могли отменить, пока шла обработка предыдущих аккаунтов
+		var user models.User
+		err := tx.Where("id = ? AND deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", userId, time.Now()).
+			First(&user).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if err := deleteCredentials(tx, user); err != nil {
+			return err
+		}
+		if err := households.RemoveUser(tx, user.ID); err != nil {
+			return err
+		}
+		if err := notifications.DeleteUserData(tx, user.ID); err != nil {
+			return err
+		}
+		// Личные повторяющиеся расходы больше не создаются, а правила домохозяйств
+		// продолжают действовать от имени владельца
+		personalRules := tx.Model(&models.RecurringExpense{}).Select("id").Where("user_id = ? AND household_id IS NULL", user.ID)
+		if err := tx.Where("recurring_id IN (?)", personalRules).Delete(&models.RecurringException{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND household_id IS NULL", user.ID).Delete(&models.RecurringExpense{}).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.RecurringExpense{}).Where("user_id = ?", user.ID).
+			Update("user_id", gorm.Expr("(SELECT user_id FROM household_members WHERE household_members.household_id = recurring_expenses.household_id AND role = ? ORDER BY created_at LIMIT 1)",
+				models.HouseholdRoleOwner)).Error
+		if err != nil {
+			return err
+		}
+
+		if config.GetConfig().AccountDeletion.Mode == "anonymize" {
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"username":              fmt.Sprintf("deleted-%d", user.ID),
+				"email":                 fmt.Sprintf("deleted-%d@invalid", user.ID),
+				"password":              "",
+				"email_verified":        false,
+				"totp_secret":           "",
+				"totp_enabled":          false,
+				"deletion_scheduled_at": nil,
+			}).Error
+		}
+
+		// Взаимные долги удалённого участника списываются: удаляются его доли, доли
+		// в оплаченных им расходах и погашения с его участием. Поэтому владелец,
+		// к которому переходят расходы, не наследует долги других участников
+		paidShares := tx.Model(&models.Expense{}).Select("id").Where("user_id = ? AND household_id IS NOT NULL", user.ID)
+		if err := tx.Where("user_id = ? OR expense_id IN (?)", user.ID, paidShares).Delete(&models.ExpenseShare{}).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.Expense{}).Where("user_id = ? AND household_id IS NOT NULL", user.ID).
+			Update("split_method", "").Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("from_user_id = ? OR to_user_id = ?", user.ID, user.ID).Delete(&models.Settlement{}).Error; err != nil {
+			return err
+		}
+
+		// Расходы и бюджеты домохозяйств остаются у остальных участников и переходят к владельцу
+		err = tx.Model(&models.Budget{}).Where("user_id = ? AND household_id IS NOT NULL", user.ID).
+			Update("user_id", gorm.Expr("(SELECT user_id FROM household_members WHERE household_members.household_id = budgets.household_id AND role = ? ORDER BY created_at LIMIT 1)",
+				models.HouseholdRoleOwner)).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.Expense{}).Where("user_id = ? AND household_id IS NOT NULL", user.ID).
+			Update("user_id", gorm.Expr("(SELECT user_id FROM household_members WHERE household_members.household_id = expenses.household_id AND role = ? ORDER BY created_at LIMIT 1)",
+				models.HouseholdRoleOwner)).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Expense{}).Error; err != nil {
+			return err
+		}
+		personalBudgets := tx.Model(&models.Budget{}).Select("id").Where("user_id = ? AND household_id IS NULL", user.ID)
+		if err := tx.Where("budget_id IN (?)", personalBudgets).Delete(&models.BudgetAlert{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND household_id IS NULL", user.ID).Delete(&models.Budget{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ? AND household_id IS NULL", user.ID).Delete(&models.Category{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+}
+
+// deleteCredentials удаляет всё, что позволяет войти в аккаунт
+func deleteCredentials(tx *gorm.DB, user models.User) error {
+	err := tx.Where("session_id IN (?)", tx.Model(&models.Session{}).Select("id").Where("user_id = ?", user.ID)).
+		Delete(&models.RefreshToken{}).Error
+	if err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.Session{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.APIToken{},
+		&models.UserIdentity{},
+	} {
+		if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"project/database"
+	"project/models"
+)
+
+// Области доступа персональных токенов
+const (
+	ScopeExpensesRead    = "expenses:read"
+	ScopeExpensesWrite   = "expenses:write"
+	ScopeCategoriesRead  = "categories:read"
+	ScopeCategoriesWrite = "categories:write"
+)
+
+var Scopes = []string{ScopeExpensesRead, ScopeExpensesWrite, ScopeCategoriesRead, ScopeCategoriesWrite}
+
+// Префикс отличает персональные токены от JWT в заголовке Authorization
+const apiTokenPrefix = "pat_"
+
+var (
+	ErrInvalidAPIToken = errors.New("invalid api token")
+	ErrInvalidScope    = errors.New("invalid scope")
+)
+
+// IsAPIToken сообщает, является ли строка персональным токеном доступа
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, apiTokenPrefix)
+}
+
+// TokenScopes возвращает области доступа токена
+func TokenScopes(token models.APIToken) []string {
+	return strings.Fields(token.Scopes)
+}
+
+// HasScope проверяет, выдана ли токену область доступа
+func HasScope(token models.APIToken, scope string) bool {
+	return slices.Contains(TokenScopes(token), scope)
+}
+
+// CreateAPIToken выпускает персональный токен; сам токен возвращается только один раз
+func CreateAPIToken(userId uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, "", ErrInvalidScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+
+	random, err := RandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	rawToken := apiTokenPrefix + random
+
+	token := models.APIToken{
+		UserID:    userId,
+		Name:      name,
+		TokenHash: HashToken(rawToken),
+		Prefix:    rawToken[:len(apiTokenPrefix)+6],
+		Scopes:    strings.Join(unique, " "),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.DB.Create(&token).Error; err != nil {
+		return nil, "", err
+	}
+	return &token, rawToken, nil
+}
+
+// AuthenticateAPIToken находит действующий токен и отмечает время его использования
+func AuthenticateAPIToken(rawToken string) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := database.DB.Where("token_hash = ?", HashToken(rawToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	token.LastUsedAt = &now
+	if err := database.DB.Model(&token).Update("last_used_at", now).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListAPITokens возвращает неотозванные токены пользователя
+func ListAPITokens(userId uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userId).
+		Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeAPIToken отзывает токен пользователя
+func RevokeAPIToken(userId, tokenId uint) error {
+	result := database.DB.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenId, userId).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"project/config"
+	"project/database"
+	"project/models"
+)
+
+// Аудитория подписанных ссылок подтверждения почты; не даёт использовать их как access-токены
+const emailVerificationAudience = "email_verification"
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrVerificationThrottled    = errors.New("verification email was sent recently")
+)
+
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// NewEmailVerificationToken подписывает токен подтверждения для текущего адреса пользователя
+func NewEmailVerificationToken(user models.User) (string, error) {
+	now := time.Now()
+	return signToken(emailVerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(int(user.ID)),
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.GetConfig().EmailVerification.Expiration)),
+		},
+	})
+}
+
+// VerifyEmail проверяет подпись токена и отмечает почту пользователя подтверждённой.
+// Токен, выданный для прежнего адреса, не принимается
+func VerifyEmail(tokenString string) (*models.User, error) {
+	claims := &emailVerificationClaims{}
+	if err := parseToken(tokenString, claims, jwt.WithAudience(emailVerificationAudience)); err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", claims.Subject).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+	if user.Email != claims.Email {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		if err := database.DB.Model(&user).Update("email_verified", true).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// ReserveVerificationEmail атомарно отмечает отправку письма подтверждения,
+// если с предыдущей отправки прошло не меньше настроенного интервала
+func ReserveVerificationEmail(userId uint) error {
+	now := time.Now()
+	threshold := now.Add(-config.GetConfig().EmailVerification.ResendInterval)
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)", userId, threshold).
+		Update("verification_sent_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVerificationThrottled
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"project/config"
+)
+
+// signingKey — ключ из набора; signKey пуст у ключей, используемых только для проверки
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// Набор ключей загружается один раз при старте в LoadKeys
+var keyring struct {
+	active *signingKey
+	byKid  map[string]*signingKey
+	// Ключ для токенов без заголовка kid, выданных до перехода на набор ключей
+	legacy  *signingKey
+	methods []string
+}
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// LoadKeys загружает ключи подписи из конфигурации. Если ключи не заданы,
+// токены подписываются и проверяются секретом HS256
+func LoadKeys() error {
+	jwtConfig := config.GetConfig().JWT
+	keyring.byKid = map[string]*signingKey{}
+	keyring.active = nil
+	keyring.legacy = nil
+	keyring.methods = nil
+
+	if jwtConfig.Secret != "" {
+		keyring.legacy = &signingKey{
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(jwtConfig.Secret),
+			verifyKey: []byte(jwtConfig.Secret),
+		}
+	}
+
+	for _, keyConfig := range jwtConfig.Keys {
+		key, err := loadKey(keyConfig)
+		if err != nil {
+			return fmt.Errorf("jwt key %q: %w", keyConfig.KID, err)
+		}
+		if _, ok := keyring.byKid[key.kid]; ok {
+			return fmt.Errorf("jwt key %q: duplicate kid", key.kid)
+		}
+		keyring.byKid[key.kid] = key
+		if keyConfig.Active {
+			if keyring.active != nil {
+				return errors.New("more than one active jwt key")
+			}
+			if key.signKey == nil {
+				return fmt.Errorf("jwt key %q: active key requires a private key", key.kid)
+			}
+			keyring.active = key
+		}
+	}
+
+	if keyring.active == nil {
+		if len(jwtConfig.Keys) > 0 {
+			return errors.New("no active jwt key")
+		}
+		if keyring.legacy == nil {
+			return errors.New("jwt secret or keys must be configured")
+		}
+		keyring.active = keyring.legacy
+	}
+
+	seen := map[string]bool{}
+	for _, key := range append(keyringKeys(), keyring.legacy) {
+		if key != nil && !seen[key.method.Alg()] {
+			seen[key.method.Alg()] = true
+			keyring.methods = append(keyring.methods, key.method.Alg())
+		}
+	}
+	return nil
+}
+
+// JWKS возвращает открытые асимметричные ключи для независимой проверки токенов
+func JWKS() []JWK {
+	keys := make([]JWK, 0, len(keyring.byKid))
+	for _, key := range keyringKeys() {
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+	return keys
+}
+
+// signToken подписывает claims активным ключом, указывая его kid в заголовке
+func signToken(claims jwt.Claims) (string, error) {
+	key := keyring.active
+	if key == nil {
+		return "", errors.New("jwt keys are not loaded")
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.kid != "" {
+		token.Header["kid"] = key.kid
+	}
+	return token.SignedString(key.signKey)
+}
+
+// parseToken проверяет подпись ключом, указанным в kid, и соответствие алгоритма этому ключу
+func parseToken(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) error {
+	options = append(options, jwt.WithValidMethods(keyring.methods), jwt.WithExpirationRequired())
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		key := keyring.legacy
+		if kid, ok := token.Header["kid"].(string); ok {
+			key = keyring.byKid[kid]
+		}
+		if key == nil {
+			return nil, errUnknownKey
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.verifyKey, nil
+	}, options...)
+	return err
+}
+
+func keyringKeys() []*signingKey {
+	keys := make([]*signingKey, 0, len(keyring.byKid))
+	for _, keyConfig := range config.GetConfig().JWT.Keys {
+		if key, ok := keyring.byKid[keyConfig.KID]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func loadKey(keyConfig config.JWTKey) (*signingKey, error) {
+	if keyConfig.KID == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &signingKey{kid: keyConfig.KID}
+
+	switch keyConfig.Algorithm {
+	case "HS256":
+		if keyConfig.Secret == "" {
+			return nil, errors.New("secret is required for HS256")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(keyConfig.Secret)
+		key.verifyKey = []byte(keyConfig.Secret)
+		return key, nil
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", keyConfig.Algorithm)
+	}
+
+	if keyConfig.PrivateKeyFile != "" {
+		privateKey, err := readPrivateKey(keyConfig.PrivateKeyFile, keyConfig.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey = privateKey
+		key.verifyKey = privateKey.Public()
+		return key, nil
+	}
+	if keyConfig.PublicKeyFile != "" {
+		data, err := os.ReadFile(keyConfig.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if keyConfig.Algorithm == "RS256" {
+			key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		} else {
+			key.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	return nil, errors.New("private_key_file or public_key_file is required")
+}
+
+func readPrivateKey(path, algorithm string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if algorithm == "RS256" {
+		return jwt.ParseRSAPrivateKeyFromPEM(data)
+	}
+	privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported EdDSA private key")
+	}
+	return signer, nil
+}
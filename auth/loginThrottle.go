@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/config"
+	"project/database"
+	"project/logging"
+	"project/models"
+)
+
+// Период удаления записей о неудачных входах, которые уже ни на что не влияют
+const loginThrottleCleanupInterval = 10 * time.Minute
+
+// Хеш, с которым сравнивается пароль при входе с неизвестным email, чтобы
+// время ответа не выдавало существование аккаунта
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// CompareDummyPassword тратит на проверку столько же времени, сколько проверка настоящего пароля
+func CompareDummyPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+}
+
+// LoginRetryAfter возвращает, сколько осталось ждать до следующей попытки входа
+// для аккаунта и IP-адреса; ноль означает, что попытка разрешена
+func LoginRetryAfter(email, ip string) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	err := database.DB.Where("key IN ?", []string{emailThrottleKey(email), ipThrottleKey(ip)}).
+		Find(&throttles).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var retryAfter time.Duration
+	now := time.Now()
+	for _, throttle := range throttles {
+		if throttle.BlockedUntil != nil && throttle.BlockedUntil.After(now) {
+			retryAfter = max(retryAfter, throttle.BlockedUntil.Sub(now))
+		}
+	}
+	return retryAfter, nil
+}
+
+// RegisterLoginFailure учитывает неудачную попытку входа: каждая следующая неудача
+// удваивает задержку, а при достижении порога аккаунт или IP блокируются
+func RegisterLoginFailure(email, ip string) error {
+	protection := config.GetConfig().LoginProtection
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := registerFailure(tx, emailThrottleKey(email), protection.MaxFailures); err != nil {
+			return err
+		}
+		return registerFailure(tx, ipThrottleKey(ip), protection.IPMaxFailures)
+	})
+}
+
+// ResetLoginFailures сбрасывает счётчики аккаунта и IP-адреса после успешного входа
+func ResetLoginFailures(email, ip string) error {
+	return database.DB.Where("key IN ?", []string{emailThrottleKey(email), ipThrottleKey(ip)}).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// StartLoginThrottleCleanup запускает фоновое удаление записей о неудачных входах.
+// Без него перебор выдуманных email или адресов неограниченно растил бы таблицу
+func StartLoginThrottleCleanup() {
+	go func() {
+		ticker := time.NewTicker(loginThrottleCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := cleanupLoginThrottles(); err != nil {
+				logging.Logger.Error("Failed to clean up login throttles", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// cleanupLoginThrottles удаляет записи, блокировка по которым истекла, а счётчик
+// всё равно был бы сброшен при следующей неудаче
+func cleanupLoginThrottles() error {
+	now := time.Now()
+	return database.DB.
+		Where("last_failure_at < ? AND (blocked_until IS NULL OR blocked_until <= ?)",
+			now.Add(-config.GetConfig().LoginProtection.ResetAfter), now).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+func registerFailure(tx *gorm.DB, key string, maxFailures int) error {
+	protection := config.GetConfig().LoginProtection
+	now := time.Now()
+
+	throttle := models.LoginThrottle{Key: key, LastFailureAt: now}
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&throttle).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&throttle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if now.Sub(throttle.LastFailureAt) > protection.ResetAfter {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+
+	blockedUntil := now.Add(backoffDelay(throttle.Failures))
+	if throttle.Failures >= maxFailures {
+		blockedUntil = now.Add(max(protection.LockoutDuration, backoffDelay(throttle.Failures)))
+	}
+	throttle.BlockedUntil = &blockedUntil
+
+	return tx.Save(&throttle).Error
+}
+
+func backoffDelay(failures int) time.Duration {
+	protection := config.GetConfig().LoginProtection
+	delay := protection.BackoffBase
+	for i := 1; i < failures && delay < protection.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, protection.BackoffMax)
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"project/config"
+	"project/database"
+	"project/models"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// CreatePasswordResetToken выпускает новый токен сброса пароля, делая
+// недействительными все ранее выданные неиспользованные токены пользователя
+func CreatePasswordResetToken(userId uint) (string, error) {
+	rawToken, err := RandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userId).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    userId,
+			TokenHash: HashToken(rawToken),
+			ExpiresAt: now.Add(config.GetConfig().PasswordReset.Expiration),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// ResetPassword погашает токен сброса, устанавливает новый пароль и
+// завершает все сессии пользователя
+func ResetPassword(rawToken, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var resetToken models.PasswordResetToken
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", HashToken(rawToken)).First(&resetToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+
+		now := time.Now()
+		if now.After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", resetToken.UserID).
+			Update("password", string(hashedPassword)).Error
+	})
+	if err != nil {
+		return err
+	}
+	return RevokeAllSessions(resetToken.UserID)
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+	"project/database"
+	"project/logging"
+	"project/models"
+)
+
+// Период синхронизации кеша отозванных токенов с БД и очистки истёкших записей
+const revocationSyncInterval = 30 * time.Second
+
+// Отозванные токены хранятся в таблице revoked_tokens; кеш в памяти избавляет
+// от запроса к БД на каждый запрос и периодически догружает записи, сделанные
+// другими экземплярами сервиса
+var revoked = struct {
+	sync.RWMutex
+	tokens   map[string]time.Time
+	syncedAt time.Time
+}{tokens: map[string]time.Time{}}
+
+// StartRevocationStore загружает отозванные токены из БД и запускает фоновую
+// синхронизацию и очистку
+func StartRevocationStore() error {
+	if err := syncRevokedTokens(); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(revocationSyncInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := syncRevokedTokens(); err != nil {
+				logging.Logger.Error("Failed to sync revoked tokens", zap.Error(err))
+			}
+			if err := cleanupRevokedTokens(); err != nil {
+				logging.Logger.Error("Failed to clean up revoked tokens", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// RevokeToken отзывает access-токен с идентификатором jti до момента его истечения
+func RevokeToken(jti string, userId uint, expiresAt time.Time) error {
+	if jti == "" || time.Now().After(expiresAt) {
+		return nil
+	}
+	record := models.RevokedToken{JTI: jti, UserID: userId, ExpiresAt: expiresAt}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return err
+	}
+
+	revoked.Lock()
+	revoked.tokens[jti] = expiresAt
+	revoked.Unlock()
+	return nil
+}
+
+// IsTokenRevoked проверяет jti по кешу отозванных токенов
+func IsTokenRevoked(jti string) bool {
+	revoked.RLock()
+	defer revoked.RUnlock()
+	_, ok := revoked.tokens[jti]
+	return ok
+}
+
+func syncRevokedTokens() error {
+	revoked.RLock()
+	since := revoked.syncedAt
+	revoked.RUnlock()
+
+	// Небольшое перекрытие окна защищает от записей, закоммиченных с задержкой
+	now := time.Now()
+	var records []models.RevokedToken
+	err := database.DB.Where("expires_at > ? AND created_at >= ?", now, since.Add(-revocationSyncInterval)).
+		Find(&records).Error
+	if err != nil {
+		return err
+	}
+
+	revoked.Lock()
+	defer revoked.Unlock()
+	for _, record := range records {
+		revoked.tokens[record.JTI] = record.ExpiresAt
+	}
+	revoked.syncedAt = now
+	return nil
+}
+
+func cleanupRevokedTokens() error {
+	now := time.Now()
+	revoked.Lock()
+	for jti, expiresAt := range revoked.tokens {
+		if now.After(expiresAt) {
+			delete(revoked.tokens, jti)
+		}
+	}
+	revoked.Unlock()
+
+	return database.DB.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/config"
+	"project/database"
+	"project/logging"
+	"project/models"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrAccountDisabled     = errors.New("account is disabled")
+)
+
+// TokenPair — результат входа или обновления токенов
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	SessionID        uint
+}
+
+// StartSession создаёт новую сессию и выпускает для неё первую пару токенов
+func StartSession(user models.User, userAgent, ip string) (*TokenPair, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(config.GetConfig().JWT.RefreshExpiration),
+	}
+
+	var refreshToken string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = createRefreshToken(tx, &session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	session.User = user
+	return issuePair(&session, refreshToken)
+}
+
+// RotateRefreshToken обменивает refresh-токен на новую пару. Повторное
+// предъявление уже использованного токена отзывает всю сессию. Заблокированный
+// аккаунт и аккаунт, срок удаления которого наступил, новых токенов не получают
+func RotateRefreshToken(rawToken, userAgent, ip string) (*TokenPair, error) {
+	var stored models.RefreshToken
+	if err := database.DB.Preload("Session").Where("token_hash = ?", HashToken(rawToken)).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	session := stored.Session
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) || now.After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var refreshToken string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Пользователь загружается заново: его могли заблокировать после выдачи токена
+		if err := tx.Where("id = ?", session.UserID).First(&session.User).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if session.User.DisabledAt != nil {
+			return ErrAccountDisabled
+		}
+		if session.User.DeletionScheduledAt != nil && !now.Before(*session.User.DeletionScheduledAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", stored.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		session.UserAgent = userAgent
+		session.IP = ip
+		session.LastUsedAt = now
+		if err := tx.Model(&session).Select("user_agent", "ip", "last_used_at").Updates(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		refreshToken, err = createRefreshToken(tx, &session)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		logging.Logger.Warn("Refresh token reuse detected, revoking session",
+			zap.Uint("session_id", session.ID),
+			zap.Uint("user_id", session.UserID),
+		)
+		if err := RevokeSession(session.UserID, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issuePair(&session, refreshToken)
+}
+
+// ActiveSessions возвращает неотозванные и неистёкшие сессии пользователя
+func ActiveSessions(userId uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// IsSessionActive сообщает, можно ли принимать access-токены сессии
+func IsSessionActive(userId, sessionId uint) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSession отзывает сессию вместе со всеми её refresh-токенами
+func RevokeSession(userId, sessionId uint) error {
+	result := database.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions отзывает все сессии пользователя и увеличивает версию его
+// токенов, так что ранее выданные access-токены перестают приниматься
+func RevokeAllSessions(userId uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userId).
+			Update("token_version", gorm.Expr("token_version + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userId).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме указанной
+func RevokeOtherSessions(userId, keepSessionId uint) (int64, error) {
+	result := database.DB.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, keepSessionId).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// HashToken возвращает SHA-256 хеш непрозрачного токена для хранения в БД
+func HashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomToken генерирует криптографически стойкий непрозрачный токен
+func RandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func createRefreshToken(tx *gorm.DB, session *models.Session) (string, error) {
+	rawToken, err := RandomToken()
+	if err != nil {
+		return "", err
+	}
+	refreshToken := models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: HashToken(rawToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+func issuePair(session *models.Session, refreshToken string) (*TokenPair, error) {
+	accessToken, accessExpiresAt, err := NewAccessToken(session.User, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		SessionID:        session.ID,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"project/config"
+	"project/models"
+)
+
+// Claims — содержимое access-токена
+type Claims struct {
+	SessionID    uint `json:"sid,omitempty"`
+	TokenVersion uint `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// UserID возвращает идентификатор пользователя из claim "sub"
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid subject claim")
+	}
+	return uint(id), nil
+}
+
+// NewAccessToken выпускает короткоживущий access-токен для сессии пользователя
+func NewAccessToken(user models.User, sessionId uint) (string, time.Time, error) {
+	jwtConfig := config.GetConfig().JWT
+	now := time.Now()
+	expirationTime := now.Add(jwtConfig.Expiration)
+
+	jti, err := RandomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token, err := signToken(Claims{
+		SessionID:    sessionId,
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(int(user.ID)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expirationTime, nil
+}
+
+// ParseAccessToken проверяет подпись, алгоритм и срок действия access-токена
+func ParseAccessToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := parseToken(tokenString, claims); err != nil {
+		return nil, err
+	}
+	// Токены с аудиторией выпускаются для других целей (подтверждение почты, второй фактор)
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("token is not an access token")
+	}
+	return claims, nil
+}
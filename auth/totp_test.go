@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет из тестовых векторов RFC 6238 для SHA-1: ASCII "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// В RFC коды восьмизначные; шестизначный код — их последние шесть цифр
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, vector := range rfcVectors {
+		if got := totpCode(key, vector.unix/totpPeriod); got != vector.code {
+			t.Errorf("totpCode(T=%d) = %s, want %s", vector.unix, got, vector.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, vector := range rfcVectors {
+		now := time.Unix(vector.unix, 0)
+		step, ok := ValidateTOTP(rfcSecret, vector.code, now, 0)
+		if !ok || step != vector.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(T=%d) = %d, %v, want %d, true", vector.unix, step, ok, vector.unix/totpPeriod)
+		}
+	}
+
+	// Код шага 1111111109/30 = 37037036
+	const code = "081804"
+	const codeStep = 37037036
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      int64
+		lastStep int64
+		ok       bool
+	}{
+		{"current step", rfcSecret, code, codeStep * totpPeriod, 0, true},
+		{"lowercase secret", strings.ToLower(rfcSecret), code, codeStep * totpPeriod, 0, true},
+		{"previous step", rfcSecret, code, (codeStep + 1) * totpPeriod, 0, true},
+		{"next step", rfcSecret, code, (codeStep - 1) * totpPeriod, 0, true},
+		{"two steps late", rfcSecret, code, (codeStep + 2) * totpPeriod, 0, false},
+		{"two steps early", rfcSecret, code, (codeStep - 2) * totpPeriod, 0, false},
+		// Код уже принятого шага и более ранних шагов повторно не принимается
+		{"replayed step", rfcSecret, code, codeStep * totpPeriod, codeStep, false},
+		{"newer step used", rfcSecret, code, codeStep * totpPeriod, codeStep + 1, false},
+		{"older step used", rfcSecret, code, codeStep * totpPeriod, codeStep - 1, true},
+		{"wrong code", rfcSecret, "081805", codeStep * totpPeriod, 0, false},
+		{"short code", rfcSecret, "81804", codeStep * totpPeriod, 0, false},
+		{"long code", rfcSecret, "0081804", codeStep * totpPeriod, 0, false},
+		{"invalid secret", "not base32!", code, codeStep * totpPeriod, 0, false},
+	}
+	for _, test := range tests {
+		step, ok := ValidateTOTP(test.secret, test.code, time.Unix(test.now, 0), test.lastStep)
+		if ok != test.ok {
+			t.Errorf("%s: ValidateTOTP ok = %v, want %v", test.name, ok, test.ok)
+			continue
+		}
+		if ok && step != codeStep {
+			t.Errorf("%s: ValidateTOTP step = %d, want %d", test.name, step, codeStep)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("GenerateTOTPSecret() = %q, decoded %d bytes, %v", secret, len(key), err)
+	}
+	now := time.Unix(1700000000, 0)
+	if _, ok := ValidateTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now, 0); !ok {
+		t.Error("code for generated secret rejected")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcde-fghij", "abcdefghij"},
+		{"ABCDE FGHIJ", "abcdefghij"},
+		{" abcdefghij", "abcdefghij"},
+	}
+	for _, test := range tests {
+		if got := normalizeRecoveryCode(test.code); got != test.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", test.code, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/config"
+	"project/database"
+	"project/models"
+)
+
+const (
+	// Аудитория токена промежуточного шага входа; не даёт использовать его как access-токен
+	twoFactorChallengeAudience = "two_factor_challenge"
+	recoveryCodeCount          = 10
+	recoveryCodeAlphabet       = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment was not started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallenge        = errors.New("invalid or expired two-factor challenge")
+)
+
+// BeginTOTPEnrollment генерирует новый секрет и сохраняет его до подтверждения кодом
+func BeginTOTPEnrollment(user models.User) (secret string, uri string, err error) {
+	if user.TOTPEnabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+	secret, err = GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	err = database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error
+	if err != nil {
+		return "", "", err
+	}
+	return secret, TOTPProvisioningURI(config.GetConfig().TwoFactor.Issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment включает двухфакторную аутентификацию, если код соответствует
+// сохранённому секрету, и возвращает новый набор кодов восстановления
+func ConfirmTOTPEnrollment(user models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	step, ok := ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// DisableTOTP отключает двухфакторную аутентификацию и удаляет коды восстановления
+func DisableTOTP(userId uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userId).
+			Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes заменяет все коды восстановления пользователя новыми
+func RegenerateRecoveryCodes(userId uint) ([]string, error) {
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userId)
+		return err
+	})
+	return codes, err
+}
+
+// VerifySecondFactor принимает текущий код TOTP или неиспользованный код восстановления.
+// Принятый код помечается использованным
+func VerifySecondFactor(user models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		// Условное обновление не даёт принять один код в двух параллельных запросах
+		result := database.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	result := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// NewTwoFactorChallenge выпускает короткоживущий токен, подтверждающий, что пароль
+// уже проверен и осталось предъявить второй фактор
+func NewTwoFactorChallenge(user models.User) (string, error) {
+	now := time.Now()
+	jti, err := RandomToken()
+	if err != nil {
+		return "", err
+	}
+	return signToken(jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   strconv.Itoa(int(user.ID)),
+		Audience:  jwt.ClaimStrings{twoFactorChallengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(config.GetConfig().TwoFactor.ChallengeExpiration)),
+	})
+}
+
+// ConsumeTwoFactorChallenge проверяет токен промежуточного шага, помечает его
+// использованным и возвращает пользователя. Токен принимается один раз, даже если
+// код окажется неверным: иначе его можно было бы перебирать до истечения срока.
+// Для новой попытки нужно снова войти по паролю, а эти попытки ограничиваются
+func ConsumeTwoFactorChallenge(tokenString string) (*models.User, error) {
+	claims := &Claims{}
+	if err := parseToken(tokenString, claims, jwt.WithAudience(twoFactorChallengeAudience)); err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	userId, err := claims.UserID()
+	if err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	// Использованный токен записывается как отозванный; запись удалится после его
+	// истечения, а первичный ключ не даёт принять токен дважды параллельно
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: claims.ID, UserID: userId, ExpiresAt: claims.ExpiresAt.Time})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidChallenge
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+	return &user, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{UserID: userId, CodeHash: HashToken(normalizeRecoveryCode(code))})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func randomRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, len(buf)+1)
+	for i, b := range buf {
+		if i == len(buf)/2 {
+			code = append(code, '-')
+		}
+		code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
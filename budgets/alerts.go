@@ -0,0 +1,123 @@
+package budgets
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/database"
+	"project/expenses"
+	"project/households"
+	"project/models"
+	"project/notifications"
+	"project/reports"
+)
+
+// thresholds — пороги уведомлений в процентах от лимита, от старшего к младшему
+var thresholds = []struct {
+	percent int64
+	kind    string
+}{
+	{100, models.NotificationBudgetExceeded},
+	{80, models.NotificationBudgetWarning},
+}
+
+// CheckAlerts проверяет бюджеты, которые учитывают расход, после его записи.
+// Каждый порог срабатывает один раз за период: отметка о нём сохраняется вместе
+// с младшими порогами, а уведомление отправляется только о старшем новом пороге
+func CheckAlerts(expense models.Expense, now time.Time) error {
+	query := database.DB.Where("category_id IS NULL OR category_id = ?", expense.CategoryID)
+	if expense.HouseholdID != nil {
+		query = query.Where("household_id = ?", *expense.HouseholdID)
+	} else {
+		query = query.Where("household_id IS NULL AND user_id = ?", expense.UserID)
+	}
+	var budgets []models.Budget
+	if err := query.Find(&budgets).Error; err != nil {
+		return err
+	}
+
+	for _, budget := range budgets {
+		loc, err := Location(budget)
+		if err != nil {
+			return err
+		}
+		// Расход прошлого или будущего периода не меняет текущее состояние бюджета
+		start := reports.BucketStart(expenses.Today(now, loc), budget.Period, time.Monday)
+		if reports.BucketStart(expense.Date, budget.Period, time.Monday) != start {
+			continue
+		}
+		status, err := Compute(budget, now)
+		if err != nil {
+			return err
+		}
+
+		var crossed string
+		for _, threshold := range thresholds {
+			if status.Spent.Minor()*100 < status.Limit.Minor()*threshold.percent {
+				continue
+			}
+			result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.BudgetAlert{
+				BudgetID:    budget.ID,
+				Threshold:   int(threshold.percent),
+				PeriodStart: start,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 && crossed == "" {
+				crossed = threshold.kind
+			}
+		}
+		if crossed != "" {
+			if err := notify(budget, status, crossed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ResetAlerts удаляет отметки о сработавших порогах, например после изменения лимита
+func ResetAlerts(tx *gorm.DB, budgetIds interface{}) error {
+	return tx.Where("budget_id IN (?)", budgetIds).Delete(&models.BudgetAlert{}).Error
+}
+
+// notify отправляет уведомление владельцу личного бюджета или всем участникам домохозяйства
+func notify(budget models.Budget, status Status, kind string) error {
+	name := "Все расходы"
+	if budget.CategoryID != nil {
+		var category models.Category
+		if err := database.DB.Select("name").First(&category, *budget.CategoryID).Error; err != nil {
+			return err
+		}
+		name = category.Name
+	}
+
+	title := fmt.Sprintf("Бюджет «%s» израсходован на %.0f%%", name, status.Percent)
+	if kind == models.NotificationBudgetExceeded {
+		title = fmt.Sprintf("Бюджет «%s» превышен", name)
+	}
+	body := fmt.Sprintf("Период %s — %s: потрачено %s из %s %s (%.2f%%).\nПри текущем темпе к концу периода будет потрачено %s %s.",
+		status.PeriodStart, status.PeriodEnd, status.Spent, status.Limit, budget.Currency, status.Percent,
+		status.Projected, budget.Currency)
+
+	recipients := []uint{budget.UserID}
+	if budget.HouseholdID != nil {
+		members, err := households.Members(*budget.HouseholdID)
+		if err != nil {
+			return err
+		}
+		recipients = recipients[:0]
+		for _, member := range members {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+	for _, userId := range recipients {
+		if err := notifications.Send(userId, kind, title, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
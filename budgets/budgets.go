@@ -0,0 +1,143 @@
+package budgets
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"project/currency"
+	"project/database"
+	"project/expenses"
+	"project/households"
+	"project/models"
+	"project/money"
+	"project/reports"
+)
+
+// Status — состояние бюджета в текущем периоде
+type Status struct {
+	Budget      models.Budget `json:"budget"`
+	PeriodStart string        `json:"period_start"`
+	PeriodEnd   string        `json:"period_end"`
+	// Остаток, перенесённый из прошлых периодов
+	CarriedOver money.Amount `json:"carried_over"`
+	Limit       money.Amount `json:"limit"`
+	Spent       money.Amount `json:"spent"`
+	// Отрицательный остаток — перерасход
+	Remaining money.Amount `json:"remaining"`
+	Percent   float64      `json:"percent"`
+	// Ожидаемые расходы к концу периода при сохранении текущего темпа
+	Projected money.Amount `json:"projected"`
+}
+
+// IsValidPeriod сообщает, поддерживается ли период бюджета
+func IsValidPeriod(period string) bool {
+	switch period {
+	case models.BudgetPeriodWeek, models.BudgetPeriodMonth, models.BudgetPeriodYear:
+		return true
+	}
+	return false
+}
+
+// List возвращает бюджеты, которые видит пользователь
+func List(userId uint) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := database.DB.Scopes(households.VisibleBudgets(userId)).Order("id").Find(&budgets).Error
+	return budgets, err
+}
+
+// Location возвращает часовой пояс, в котором считаются периоды бюджета, — пояс его
+// владельца. Поэтому все участники домохозяйства видят одни и те же периоды
+func Location(budget models.Budget) (*time.Location, error) {
+	var owner models.User
+	if err := database.DB.Select("timezone").First(&owner, budget.UserID).Error; err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(owner.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// Compute считает состояние бюджета на момент now. Расходы каждого периода
+// суммируются в валюте бюджета; при переносе остатка учитываются периоды начиная
+// с периода StartDate, а перерасход на следующий период не переносится
+func Compute(budget models.Budget, now time.Time) (Status, error) {
+	loc, err := Location(budget)
+	if err != nil {
+		return Status{}, err
+	}
+	today := expenses.Today(now, loc)
+	start := reports.BucketStart(today, budget.Period, time.Monday)
+	end := reports.NextBucket(start, budget.Period).AddDate(0, 0, -1)
+
+	// Перенос остатка нелинеен, поэтому прошлые периоды суммируются по отдельности,
+	// частями не больше reports.MaxBuckets периодов
+	var carried money.Amount
+	if budget.Rollover && budget.StartDate.Before(start) {
+		from := reports.BucketStart(budget.StartDate, budget.Period, time.Monday)
+		for from.Before(start) {
+			to := from
+			for count := 0; count < reports.MaxBuckets && to.Before(start); count++ {
+				to = reports.NextBucket(to, budget.Period)
+			}
+			buckets, err := periodSums(budget, from, to.AddDate(0, 0, -1))
+			if err != nil {
+				return Status{}, err
+			}
+			for _, bucket := range buckets {
+				carried = max(budget.Amount+carried-bucket.Sum, 0)
+			}
+			from = to
+		}
+	}
+	buckets, err := periodSums(budget, start, end)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{
+		Budget:      budget,
+		PeriodStart: start.Format("2006-01-02"),
+		PeriodEnd:   end.Format("2006-01-02"),
+		CarriedOver: carried,
+		Limit:       budget.Amount + carried,
+		Spent:       buckets[0].Sum,
+	}
+	status.Remaining = status.Limit - status.Spent
+	status.Percent = math.Round(float64(status.Spent.Minor())*10000/float64(status.Limit.Minor())) / 100
+
+	elapsed := int64(today.Sub(start).Hours()/24) + 1
+	total := int64(end.Sub(start).Hours()/24) + 1
+	projected := (status.Spent.Minor()*total*2 + elapsed) / (elapsed * 2)
+	status.Projected = currency.Round(money.FromMinor(projected), budget.Currency)
+	return status, nil
+}
+
+// periodSums возвращает расходы бюджета по периодам с from по to включительно
+func periodSums(budget models.Budget, from, to time.Time) ([]reports.Bucket, error) {
+	filter := expenses.Filter{From: &from, To: &to}
+	query := database.DB.Model(&models.Expense{}).Scopes(expenseScope(budget), filter.Scope)
+	if budget.CategoryID != nil {
+		query = query.Where("expenses.category_id = ?", *budget.CategoryID)
+	}
+	series, err := reports.Timeseries(query, reports.TimeseriesOptions{
+		Bucket:    budget.Period,
+		WeekStart: time.Monday,
+		From:      &from,
+		To:        to,
+	}, budget.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return series.Buckets, nil
+}
+
+// expenseScope выбирает расходы, которые учитывает бюджет
+func expenseScope(budget models.Budget) func(*gorm.DB) *gorm.DB {
+	if budget.HouseholdID != nil {
+		return households.HouseholdExpenses(*budget.HouseholdID)
+	}
+	return households.PersonalExpenses(budget.UserID)
+}
@@ -22,12 +22,142 @@ type Config struct {
 	Server struct {
 		Port    int `yaml:"port"`
 		Timeout int `yaml:"timeout"`
+		// Внешний адрес приложения, используется для ссылок в письмах
+		PublicURL string `yaml:"public_url"`
 	} `yaml:"server"`
 
 	JWT struct {
-		Secret     string        `yaml:"secret"`
-		Expiration time.Duration `yaml:"expiration"`
+		// Секрет HS256; используется, если ключи не заданы, и для проверки токенов без kid
+		Secret            string        `yaml:"secret"`
+		Expiration        time.Duration `yaml:"expiration"`
+		RefreshExpiration time.Duration `yaml:"refresh_expiration"`
+		Keys              []JWTKey      `yaml:"keys"`
 	} `yaml:"jwt"`
+
+	Mail struct {
+		// smtp или outbox (письма сохраняются файлами в OutboxDir)
+		Driver    string `yaml:"driver"`
+		From      string `yaml:"from"`
+		OutboxDir string `yaml:"outbox_dir"`
+		SMTP      struct {
+			Host     string `yaml:"host"`
+			Port     int    `yaml:"port"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+		} `yaml:"smtp"`
+	} `yaml:"mail"`
+
+	PasswordReset struct {
+		Expiration time.Duration `yaml:"expiration"`
+	} `yaml:"password_reset"`
+
+	EmailVerification struct {
+		Expiration     time.Duration `yaml:"expiration"`
+		ResendInterval time.Duration `yaml:"resend_interval"`
+		// Запрещает пользователям с неподтверждённой почтой добавлять расходы
+		RequiredForExpenses bool `yaml:"required_for_expenses"`
+	} `yaml:"email_verification"`
+
+	TwoFactor struct {
+		// Название сервиса, отображаемое в приложении-аутентификаторе
+		Issuer              string        `yaml:"issuer"`
+		ChallengeExpiration time.Duration `yaml:"challenge_expiration"`
+	} `yaml:"two_factor"`
+
+	LoginProtection struct {
+		// Число неудачных попыток входа в аккаунт до временной блокировки
+		MaxFailures int `yaml:"max_failures"`
+		// Число неудачных попыток с одного IP до временной блокировки
+		IPMaxFailures   int           `yaml:"ip_max_failures"`
+		LockoutDuration time.Duration `yaml:"lockout_duration"`
+		// Задержка после первой неудачи, удваивается с каждой следующей
+		BackoffBase time.Duration `yaml:"backoff_base"`
+		BackoffMax  time.Duration `yaml:"backoff_max"`
+		// Счётчик неудач сбрасывается, если попыток не было дольше этого времени
+		ResetAfter time.Duration `yaml:"reset_after"`
+	} `yaml:"login_protection"`
+
+	AccountDeletion struct {
+		// Срок, в течение которого удаление аккаунта можно отменить
+		GracePeriod time.Duration `yaml:"grace_period"`
+		// delete — расходы и категории удаляются вместе с аккаунтом;
+		// anonymize — сохраняются, а личные данные аккаунта затираются
+		Mode string `yaml:"mode"`
+	} `yaml:"account_deletion"`
+
+	OIDC struct {
+		Providers []OIDCProvider `yaml:"providers"`
+	} `yaml:"oidc"`
+
+	Households struct {
+		// Срок действия ссылки-приглашения в домохозяйство
+		InvitationExpiration time.Duration `yaml:"invitation_expiration"`
+	} `yaml:"households"`
+
+	Currency struct {
+		// Базовая валюта новых пользователей и домохозяйств
+		Default string `yaml:"default"`
+		// Источник курсов: csv — файл RatesFile; none — курсы загружаются только вручную
+		Provider  string `yaml:"provider"`
+		RatesFile string `yaml:"rates_file"`
+		// Период повторной загрузки курсов из источника
+		SyncInterval time.Duration `yaml:"sync_interval"`
+	} `yaml:"currency"`
+
+	Recurring struct {
+		// Период проверки наступивших повторений расходов
+		CheckInterval time.Duration `yaml:"check_interval"`
+	} `yaml:"recurring"`
+
+	Import struct {
+		// Максимальное число строк в одном импортируемом файле
+		MaxRows int `yaml:"max_rows"`
+	} `yaml:"import"`
+
+	Notifications struct {
+		// Максимальное время ожидания ответа вебхука
+		WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+		// Разрешает вебхуки на адреса внутренней сети, например при локальной разработке
+		AllowPrivateWebhooks bool `yaml:"allow_private_webhooks"`
+	} `yaml:"notifications"`
+
+	Admin struct {
+		// Пользователи с этими адресами получают роль администратора при запуске
+		Emails []string `yaml:"emails"`
+	} `yaml:"admin"`
+
+	RateLimit struct {
+		Max    int           `yaml:"max"`
+		Window time.Duration `yaml:"window"`
+	} `yaml:"rate_limit"`
+}
+
+// JWTKey описывает ключ подписи токенов. Активным может быть только один ключ,
+// остальные используются лишь для проверки ранее выданных токенов
+type JWTKey struct {
+	KID string `yaml:"kid"`
+	// RS256, EdDSA или HS256
+	Algorithm string `yaml:"algorithm"`
+	// PEM-файл закрытого ключа (PKCS#8 или PKCS#1); обязателен для активного ключа
+	PrivateKeyFile string `yaml:"private_key_file"`
+	// PEM-файл открытого ключа; достаточен для ключа, используемого только для проверки
+	PublicKeyFile string `yaml:"public_key_file"`
+	// Секрет для ключа HS256
+	Secret string `yaml:"secret"`
+	Active bool   `yaml:"active"`
+}
+
+// OIDCProvider описывает внешнего провайдера OpenID Connect
+type OIDCProvider struct {
+	// Имя провайдера в URL: /api/oidc/<name>/login
+	Name string `yaml:"name"`
+	// Адрес издателя; документ обнаружения загружается с <issuer_url>/.well-known/openid-configuration
+	IssuerURL    string `yaml:"issuer_url"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// Адрес обратного вызова, зарегистрированный у провайдера: <public_url>/api/oidc/<name>/callback
+	RedirectURL string   `yaml:"redirect_url"`
+	Scopes      []string `yaml:"scopes"`
 }
 
 // Объявляем переменные для Singleton
@@ -61,6 +191,78 @@ func LoadConfig(filename string) *Config {
 		if err != nil {
 			log.Fatalf("Ошибка парсинга JWT Expiration: %v", err)
 		}
+		if configInstance.JWT.RefreshExpiration == 0 {
+			configInstance.JWT.RefreshExpiration = 30 * 24 * time.Hour
+		}
+		if configInstance.PasswordReset.Expiration == 0 {
+			configInstance.PasswordReset.Expiration = time.Hour
+		}
+		if configInstance.EmailVerification.Expiration == 0 {
+			configInstance.EmailVerification.Expiration = 48 * time.Hour
+		}
+		if configInstance.EmailVerification.ResendInterval == 0 {
+			configInstance.EmailVerification.ResendInterval = time.Minute
+		}
+		if configInstance.TwoFactor.Issuer == "" {
+			configInstance.TwoFactor.Issuer = "Expenses Calc"
+		}
+		if configInstance.TwoFactor.ChallengeExpiration == 0 {
+			configInstance.TwoFactor.ChallengeExpiration = 5 * time.Minute
+		}
+		if configInstance.LoginProtection.MaxFailures == 0 {
+			configInstance.LoginProtection.MaxFailures = 5
+		}
+		if configInstance.LoginProtection.IPMaxFailures == 0 {
+			configInstance.LoginProtection.IPMaxFailures = 20
+		}
+		if configInstance.LoginProtection.LockoutDuration == 0 {
+			configInstance.LoginProtection.LockoutDuration = 15 * time.Minute
+		}
+		if configInstance.LoginProtection.BackoffBase == 0 {
+			configInstance.LoginProtection.BackoffBase = time.Second
+		}
+		if configInstance.LoginProtection.BackoffMax == 0 {
+			configInstance.LoginProtection.BackoffMax = 5 * time.Minute
+		}
+		if configInstance.LoginProtection.ResetAfter == 0 {
+			configInstance.LoginProtection.ResetAfter = time.Hour
+		}
+		if configInstance.AccountDeletion.GracePeriod == 0 {
+			configInstance.AccountDeletion.GracePeriod = 30 * 24 * time.Hour
+		}
+		if configInstance.AccountDeletion.Mode == "" {
+			configInstance.AccountDeletion.Mode = "delete"
+		}
+		if mode := configInstance.AccountDeletion.Mode; mode != "delete" && mode != "anonymize" {
+			log.Fatalf("Неизвестный режим удаления аккаунта: %s", mode)
+		}
+		if configInstance.Households.InvitationExpiration == 0 {
+			configInstance.Households.InvitationExpiration = 7 * 24 * time.Hour
+		}
+		if configInstance.Currency.Default == "" {
+			configInstance.Currency.Default = "RUB"
+		}
+		if configInstance.Currency.Provider == "" {
+			configInstance.Currency.Provider = "none"
+		}
+		if configInstance.Currency.SyncInterval == 0 {
+			configInstance.Currency.SyncInterval = 24 * time.Hour
+		}
+		if configInstance.Recurring.CheckInterval == 0 {
+			configInstance.Recurring.CheckInterval = time.Hour
+		}
+		if configInstance.Import.MaxRows == 0 {
+			configInstance.Import.MaxRows = 10000
+		}
+		if configInstance.Notifications.WebhookTimeout == 0 {
+			configInstance.Notifications.WebhookTimeout = 10 * time.Second
+		}
+		if configInstance.RateLimit.Max == 0 {
+			configInstance.RateLimit.Max = 10
+		}
+		if configInstance.RateLimit.Window == 0 {
+			configInstance.RateLimit.Window = time.Minute
+		}
 	})
 	return configInstance
 }
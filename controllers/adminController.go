@@ -0,0 +1,365 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/auth"
+	"project/database"
+	"project/expenses"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"project/money"
+	"strconv"
+	"time"
+)
+
+func AdminGetUsers(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to get users")
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid limit",
+		})
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid offset",
+		})
+	}
+
+	query := database.DB.Model(&models.User{})
+	if search := c.Query("q"); search != "" {
+		pattern := "%" + expenses.EscapeLike(search) + "%"
+		query = query.Where(`username ILIKE ? ESCAPE '\' OR email ILIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	var users []models.User
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"users": users,
+		"total": total,
+	})
+}
+
+func AdminDisableUser(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to disable user")
+
+	user, found, err := findUserByParam(c)
+	if !found {
+		return err
+	}
+	if user.ID == middleware.CurrentUserID(c) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot disable yourself",
+		})
+	}
+
+	if user.DisabledAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&user).Update("disabled_at", now).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to disable user",
+			})
+		}
+		user.DisabledAt = &now
+		if err := auth.RevokeAllSessions(user.ID); err != nil {
+			logging.Logger.Error("Failed to revoke sessions:", zap.Error(err))
+		}
+	}
+
+	logging.Logger.Info("User disabled",
+		zap.Uint("user_id", user.ID),
+		zap.Uint("admin_id", middleware.CurrentUserID(c)),
+	)
+	return c.JSON(user)
+}
+
+func AdminEnableUser(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to enable user")
+
+	user, found, err := findUserByParam(c)
+	if !found {
+		return err
+	}
+	if err := database.DB.Model(&user).Update("disabled_at", nil).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to enable user",
+		})
+	}
+	user.DisabledAt = nil
+
+	logging.Logger.Info("User enabled",
+		zap.Uint("user_id", user.ID),
+		zap.Uint("admin_id", middleware.CurrentUserID(c)),
+	)
+	return c.JSON(user)
+}
+
+func AdminSetUserRole(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to change user role")
+
+	user, found, err := findUserByParam(c)
+	if !found {
+		return err
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	role := data["role"]
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role",
+		})
+	}
+	if user.ID == middleware.CurrentUserID(c) && role != models.RoleAdmin {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot remove your own admin role",
+		})
+	}
+
+	if err := database.DB.Model(&user).Update("role", role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update user",
+		})
+	}
+	user.Role = role
+
+	logging.Logger.Info("User role changed",
+		zap.Uint("user_id", user.ID),
+		zap.String("role", role),
+		zap.Uint("admin_id", middleware.CurrentUserID(c)),
+	)
+	return c.JSON(user)
+}
+
+func AdminGetCategories(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to get global categories")
+
+	var categories []models.Category
+	database.DB.Where("owner_id = 0").Order("id").Find(&categories)
+
+	return c.JSON(categories)
+}
+
+func AdminAddCategory(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to add global category")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["name"] == "" || data["description"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	existingCategory := models.Category{}
+	if err := database.DB.Where("name = ? AND owner_id = 0", data["name"]).First(&existingCategory).Error; err == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Category already exists",
+		})
+	}
+
+	category := models.Category{
+		Name:        data["name"],
+		Description: data["description"],
+		OwnerId:     0,
+	}
+	if err := database.DB.Create(&category).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create category",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+func AdminUpdateCategory(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to update global category")
+
+	category, found, err := findGlobalCategoryByParam(c)
+	if !found {
+		return err
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["name"] != "" && data["name"] != category.Name {
+		existingCategory := models.Category{}
+		if err := database.DB.Where("name = ? AND owner_id = 0", data["name"]).First(&existingCategory).Error; err == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Category already exists",
+			})
+		}
+		category.Name = data["name"]
+	}
+	if data["description"] != "" {
+		category.Description = data["description"]
+	}
+
+	if err := database.DB.Save(&category).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update category",
+		})
+	}
+	return c.JSON(category)
+}
+
+func AdminDeleteCategory(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to delete global category")
+
+	category, found, err := findGlobalCategoryByParam(c)
+	if !found {
+		return err
+	}
+
+	// Категорию нельзя удалить, пока на неё ссылаются расходы, бюджеты,
+	// повторяющиеся расходы или изменённые повторения
+	counts := fiber.Map{}
+	var total int64
+	for key, model := range map[string]interface{}{
+		"expense_count":             &models.Expense{},
+		"budget_count":              &models.Budget{},
+		"recurring_count":           &models.RecurringExpense{},
+		"recurring_exception_count": &models.RecurringException{},
+	} {
+		var count int64
+		if err := database.DB.Model(model).Where("category_id = ?", category.ID).Count(&count).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		counts[key] = count
+		total += count
+	}
+	if total > 0 {
+		counts["error"] = "Category is in use"
+		return c.Status(fiber.StatusConflict).JSON(counts)
+	}
+
+	if err := database.DB.Delete(&category).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete category",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Category deleted successfully",
+	})
+}
+
+func AdminGetStats(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to get statistics")
+
+	var users, disabledUsers, admins, activeSessions, expenses, categories int64
+	var expensesSum []struct {
+		Currency string       `json:"currency"`
+		Sum      money.Amount `json:"sum"`
+	}
+	now := time.Now()
+	counts := []struct {
+		query *gorm.DB
+		dest  *int64
+	}{
+		{database.DB.Model(&models.User{}), &users},
+		{database.DB.Model(&models.User{}).Where("disabled_at IS NOT NULL"), &disabledUsers},
+		{database.DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin), &admins},
+		{database.DB.Model(&models.Session{}).Where("revoked_at IS NULL AND expires_at > ?", now), &activeSessions},
+		{database.DB.Model(&models.Expense{}), &expenses},
+		{database.DB.Model(&models.Category{}), &categories},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.dest).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+	}
+	err := database.DB.Model(&models.Expense{}).Select("currency, SUM(amount) AS sum").
+		Group("currency").Order("currency").Scan(&expensesSum).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"users":           users,
+		"disabled_users":  disabledUsers,
+		"admins":          admins,
+		"active_sessions": activeSessions,
+		"expenses":        expenses,
+		"expenses_sum":    expensesSum,
+		"categories":      categories,
+	})
+}
+
+// findUserByParam загружает пользователя по параметру :id; при неудаче ответ
+// клиенту уже отправлен и возвращается false
+func findUserByParam(c fiber.Ctx) (models.User, bool, error) {
+	var user models.User
+	userId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return user, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+		return user, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return user, true, nil
+}
+
+// findGlobalCategoryByParam загружает общую категорию (owner_id = 0) по параметру :id
+func findGlobalCategoryByParam(c fiber.Ctx) (models.Category, bool, error) {
+	var category models.Category
+	categoryId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return category, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID",
+		})
+	}
+	if err := database.DB.Where("id = ? AND owner_id = 0", categoryId).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return category, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Category not found",
+			})
+		}
+		return category, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return category, true, nil
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/auth"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"strconv"
+	"time"
+)
+
+func GetAPITokens(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get api tokens")
+
+	tokens, err := auth.ListAPITokens(middleware.CurrentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	result := make([]fiber.Map, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, apiTokenResponse(token))
+	}
+	return c.JSON(result)
+}
+
+func CreateAPIToken(c fiber.Ctx) error {
+	logging.Logger.Info("Request to create api token")
+
+	var data struct {
+		Name      string   `json:"name"`
+		Scopes    []string `json:"scopes"`
+		ExpiresAt string   `json:"expires_at"`
+	}
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data.Name == "" || len(data.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	var expiresAt *time.Time
+	if data.ExpiresAt != "" {
+		parsedDate, err := time.Parse("2006-01-02", data.ExpiresAt)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		if !parsedDate.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Expiration date must be in the future",
+			})
+		}
+		expiresAt = &parsedDate
+	}
+
+	token, rawToken, err := auth.CreateAPIToken(middleware.CurrentUserID(c), data.Name, data.Scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  "Invalid scope",
+				"scopes": auth.Scopes,
+			})
+		}
+		logging.Logger.Error("Failed to create api token:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create token",
+		})
+	}
+
+	response := apiTokenResponse(*token)
+	response["token"] = rawToken
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+func DeleteAPIToken(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete api token")
+
+	tokenId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid token ID",
+		})
+	}
+	if err := auth.RevokeAPIToken(middleware.CurrentUserID(c), uint(tokenId)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Token not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete token",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Token deleted successfully",
+	})
+}
+
+func apiTokenResponse(token models.APIToken) fiber.Map {
+	return fiber.Map{
+		"token_id":     token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       auth.TokenScopes(token),
+		"expires_at":   token.ExpiresAt,
+		"last_used_at": token.LastUsedAt,
+		"created_at":   token.CreatedAt,
+	}
+}
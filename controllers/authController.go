@@ -5,15 +5,16 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
-	"log"
+	"net/mail"
+	"project/auth"
 	"project/config"
 	"project/database"
 	"project/logging"
+	"project/middleware"
 	"project/models"
 	"strconv"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -34,6 +35,11 @@ func Register(c fiber.Ctx) error {
 			"error": "Missing required fields",
 		})
 	}
+	if !isValidEmail(data["email"]) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email address",
+		})
+	}
 	logging.Logger.Info("User information",
 		zap.String("username", data["username"]),
 		zap.String("email", data["email"]),
@@ -55,9 +61,11 @@ func Register(c fiber.Ctx) error {
 
 	logging.Logger.Info("Creating User")
 	user := &models.User{
-		Username: data["username"],
-		Email:    data["email"],
-		Password: string(hashedPassword),
+		Username:     data["username"],
+		Email:        data["email"],
+		Password:     string(hashedPassword),
+		Role:         models.RoleUser,
+		BaseCurrency: config.GetConfig().Currency.Default,
 	}
 	if err := database.DB.Create(&user).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -65,6 +73,10 @@ func Register(c fiber.Ctx) error {
 		})
 	}
 
+	if err := sendVerificationEmail(*user); err != nil {
+		logging.Logger.Error("Failed to send verification email:", zap.Error(err))
+	}
+
 	logging.Logger.Info("User registered successfully")
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "User registered successfully",
@@ -88,33 +100,114 @@ func Login(c fiber.Ctx) error {
 
 	logging.Logger.Info("User email", zap.String("email", data["email"]))
 
+	if blocked, err := checkLoginThrottle(c, data["email"]); blocked {
+		return err
+	}
+
 	var user models.User
 	database.DB.Where("email = ?", data["email"]).First(&user)
 	if user.ID == 0 {
-		logging.Logger.Warn("User not found")
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid credentials",
-		})
+		logging.Logger.Info("User not found")
+		auth.CompareDummyPassword(data["password"])
+		return loginFailed(c, data["email"])
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(data["password"]))
 	if err != nil {
-		logging.Logger.Error("Invalid Password:", zap.Error(err))
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid credentials",
+		logging.Logger.Info("Invalid password")
+		return loginFailed(c, data["email"])
+	}
+
+	// С двухфакторной аутентификацией счётчики сбрасываются только после проверки
+	// второго фактора, иначе вход по паролю обнулял бы попытки подбора кода
+	if !user.TOTPEnabled {
+		if err := auth.ResetLoginFailures(user.Email, c.IP()); err != nil {
+			logging.Logger.Error("Failed to reset login failures:", zap.Error(err))
+		}
+	}
+
+	return loginOrChallenge(c, user)
+}
+
+// loginOrChallenge открывает сессию или, если включена двухфакторная аутентификация,
+// возвращает токен промежуточного шага для POST /api/login/2fa
+func loginOrChallenge(c fiber.Ctx, user models.User) error {
+	if user.DisabledAt != nil {
+		return accountDisabled(c)
+	}
+	if user.TOTPEnabled {
+		logging.Logger.Info("Two-factor authentication required")
+		challenge, err := auth.NewTwoFactorChallenge(user)
+		if err != nil {
+			logging.Logger.Error("Error generating challenge:", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate token",
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":             "Two-factor authentication required",
+			"two_factor_required": true,
+			"challenge_token":     challenge,
 		})
 	}
 
-	logging.Logger.Info("Generating JWT token")
-	jwtConfig := config.GetConfig().JWT
-	expirationTime := time.Now().Add(jwtConfig.Expiration)
+	return completeLogin(c, user)
+}
+
+func isValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
 
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": strconv.Itoa(int(user.ID)),
-		"exp": expirationTime.Unix(),
+// checkLoginThrottle отклоняет попытку входа, если аккаунт или IP временно заблокированы;
+// в этом случае ответ клиенту уже отправлен и возвращается true
+func checkLoginThrottle(c fiber.Ctx, email string) (bool, error) {
+	retryAfter, err := auth.LoginRetryAfter(email, c.IP())
+	if err != nil {
+		logging.Logger.Error("Failed to check login throttle:", zap.Error(err))
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	if retryAfter <= 0 {
+		return false, nil
+	}
+
+	logging.Logger.Warn("Login attempt blocked", zap.String("email", email), zap.String("ip", c.IP()))
+	seconds := int(retryAfter.Round(time.Second).Seconds())
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(seconds, 1)))
+	return true, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "Too many failed login attempts, try again later",
+		"retry_after": max(seconds, 1),
+	})
+}
+
+// loginFailed учитывает неудачную попытку и возвращает одинаковый ответ для
+// неизвестного email, неверного пароля и неверного кода второго фактора
+func loginFailed(c fiber.Ctx, email string) error {
+	if err := auth.RegisterLoginFailure(email, c.IP()); err != nil {
+		logging.Logger.Error("Failed to register login failure:", zap.Error(err))
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"message": "Invalid credentials",
 	})
-	secretKey := config.GetConfig().JWT.Secret
-	token, err := claims.SignedString([]byte(secretKey))
+}
+
+func accountDisabled(c fiber.Ctx) error {
+	logging.Logger.Info("Login attempt to disabled account")
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "Account is disabled",
+	})
+}
+
+// completeLogin открывает сессию для пользователя, прошедшего все проверки входа
+func completeLogin(c fiber.Ctx, user models.User) error {
+	if user.DisabledAt != nil {
+		return accountDisabled(c)
+	}
+
+	logging.Logger.Info("Starting session")
+	pair, err := auth.StartSession(user, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
 		logging.Logger.Error("Error generating token:", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -123,115 +216,113 @@ func Login(c fiber.Ctx) error {
 	}
 
 	logging.Logger.Info("Setting cookie")
-
-	cookie := fiber.Cookie{
-		Name:     "jwt",
-		Value:    token,
-		Expires:  time.Now().Add(time.Hour * 24),
-		HTTPOnly: true,
-		Secure:   true,
-	}
-	c.Cookie(&cookie)
+	setAuthCookies(c, pair)
 
 	logging.Logger.Info("Authentication successful, returning")
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Login successful",
-	})
+	return c.Status(fiber.StatusOK).JSON(tokenResponse("Login successful", pair))
 }
 
 func User(c fiber.Ctx) error {
 	logging.Logger.Info("Request to get user...")
 
-	cookie := c.Cookies("jwt")
+	return c.JSON(middleware.CurrentUser(c))
+}
 
-	secretKey := config.GetConfig().JWT.Secret
-	token, err := jwt.ParseWithClaims(cookie, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
+func JWKS(c fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{
+		"keys": auth.JWKS(),
 	})
+}
 
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
+func Logout(c fiber.Ctx) error {
+	logging.Logger.Info("Received a logout request")
 
-	claims, ok := token.Claims.(*jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to parse claims",
-		})
+	// Токен с истёкшим сроком или неверной подписью отзывать не нужно — достаточно удалить cookie
+	if claims, err := auth.ParseAccessToken(middleware.TokenFromRequest(c)); err == nil {
+		if userId, err := claims.UserID(); err == nil {
+			if err := auth.RevokeToken(claims.ID, userId, claims.ExpiresAt.Time); err != nil {
+				logging.Logger.Error("Failed to revoke token:", zap.Error(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to revoke token",
+				})
+			}
+			if err := auth.RevokeSession(userId, claims.SessionID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				logging.Logger.Error("Failed to revoke session:", zap.Error(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to revoke session",
+				})
+			}
+		}
 	}
 
-	id, _ := strconv.Atoi((*claims)["sub"].(string))
-	user := models.User{ID: uint(id)}
+	clearAuthCookies(c)
 
-	if err := database.DB.Where("id = ?", user.ID).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
-		log.Println("Database error:", err)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Logout successful",
+	})
+}
+
+func LogoutAll(c fiber.Ctx) error {
+	logging.Logger.Info("Received a logout from all devices request")
+
+	if err := auth.RevokeAllSessions(middleware.CurrentUserID(c)); err != nil {
+		logging.Logger.Error("Failed to revoke sessions:", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving user",
+			"error": "Failed to revoke sessions",
 		})
 	}
 
-	return c.JSON(user)
+	clearAuthCookies(c)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Logged out from all devices",
+	})
 }
 
-func Logout(c fiber.Ctx) error {
-	logging.Logger.Info("Received a logout request")
+func setAuthCookies(c fiber.Ctx, pair *auth.TokenPair) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "jwt",
+		Value:    pair.AccessToken,
+		Expires:  pair.AccessExpiresAt,
+		HTTPOnly: true,
+		Secure:   true,
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    pair.RefreshToken,
+		Path:     "/api",
+		Expires:  pair.RefreshExpiresAt,
+		HTTPOnly: true,
+		Secure:   true,
+	})
+}
 
-	cookie := fiber.Cookie{
+func clearAuthCookies(c fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
 		Name:     "jwt",
 		Value:    "",
 		Expires:  time.Now().Add(-time.Hour),
 		HTTPOnly: true,
 		Secure:   true,
-	}
-	c.Cookie(&cookie)
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Logout successful",
 	})
-}
-
-func CheckUser(c fiber.Ctx) (uint, error, bool) {
-	cookie := c.Cookies("jwt")
-
-	secretKey := config.GetConfig().JWT.Secret
-	token, err := jwt.ParseWithClaims(cookie, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Path:     "/api",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Secure:   true,
 	})
+}
 
-	if err != nil {
-		return 0, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		}), true
-	}
-
-	claims, ok := token.Claims.(*jwt.MapClaims)
-	if !ok {
-		return 0, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to parse claims",
-		}), true
-	}
-
-	id, _ := strconv.Atoi((*claims)["sub"].(string))
-	user := models.User{ID: uint(id)}
-
-	if err := database.DB.Where("id = ?", user.ID).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			}), true
-		}
-		log.Println("Database error:", err)
-		return 0, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving user",
-		}), true
+func tokenResponse(message string, pair *auth.TokenPair) fiber.Map {
+	return fiber.Map{
+		"message":       message,
+		"access_token":  pair.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(time.Until(pair.AccessExpiresAt).Seconds()),
+		"refresh_token": pair.RefreshToken,
 	}
-	return uint(id), nil, false
 }
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/budgets"
+	"project/currency"
+	"project/database"
+	"project/expenses"
+	"project/households"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"strconv"
+	"time"
+)
+
+func GetBudgets(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get budgets")
+
+	list, err := budgets.List(middleware.CurrentUserID(c))
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving budgets",
+		})
+	}
+	return c.JSON(list)
+}
+
+// CreateBudget создаёт личный бюджет или, с household_id, бюджет домохозяйства.
+// Без category_id бюджет ограничивает все расходы
+func CreateBudget(c fiber.Ctx) error {
+	logging.Logger.Info("Request to create budget")
+
+	userId := middleware.CurrentUserID(c)
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["period"] == "" || data["amount"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	if !budgets.IsValidPeriod(data["period"]) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid budget period",
+		})
+	}
+
+	householdId, ok, err := householdFromValue(c, data["household_id"],
+		models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return err
+	}
+
+	budget := models.Budget{
+		UserID:      userId,
+		HouseholdID: householdId,
+		Period:      data["period"],
+		Currency:    middleware.CurrentUser(c).BaseCurrency,
+		StartDate:   expenses.Today(time.Now(), userLocation(c)),
+	}
+	if data["category_id"] != "" {
+		var category models.Category
+		if err := database.DB.Where("id = ?", data["category_id"]).
+			Scopes(households.UsableCategories(userId, householdId)).First(&category).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Category not found",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		budget.CategoryID = &category.ID
+	}
+	if householdId != nil {
+		householdCurrency, err := households.Currency(*householdId)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		budget.Currency = householdCurrency
+	}
+	if ok, err := applyBudgetFields(c, &budget, data); !ok {
+		return err
+	}
+
+	if err := database.DB.Create(&budget).Error; err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create budget",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(budget)
+}
+
+func UpdateBudget(c fiber.Ctx) error {
+	logging.Logger.Info("Request to update budget")
+
+	budget, ok, err := findEditableBudget(c)
+	if !ok {
+		return err
+	}
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["period"] != "" {
+		if !budgets.IsValidPeriod(data["period"]) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid budget period",
+			})
+		}
+		budget.Period = data["period"]
+	}
+	if ok, err := applyBudgetFields(c, &budget, data); !ok {
+		return err
+	}
+
+	// Новый лимит или период меняет пороги, поэтому они снова могут сработать
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&budget).Error; err != nil {
+			return err
+		}
+		return budgets.ResetAlerts(tx, []uint{budget.ID})
+	})
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update budget",
+		})
+	}
+	return c.JSON(budget)
+}
+
+func DeleteBudget(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete budget")
+
+	budget, ok, err := findEditableBudget(c)
+	if !ok {
+		return err
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := budgets.ResetAlerts(tx, []uint{budget.ID}); err != nil {
+			return err
+		}
+		return tx.Delete(&budget).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete budget",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Budget deleted successfully",
+	})
+}
+
+// GetBudgetStatus возвращает состояние всех бюджетов пользователя в текущем периоде
+func GetBudgetStatus(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get budget status")
+
+	list, err := budgets.List(middleware.CurrentUserID(c))
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving budgets",
+		})
+	}
+	// Периоды считаются в часовом поясе владельца бюджета, а не того, кто смотрит
+	now := time.Now()
+	statuses := make([]budgets.Status, 0, len(list))
+	for _, budget := range list {
+		status, err := budgets.Compute(budget, now)
+		if err != nil {
+			return conversionError(c, err, "Error computing budget status")
+		}
+		statuses = append(statuses, status)
+	}
+	return c.JSON(statuses)
+}
+
+// applyBudgetFields применяет к бюджету необязательные поля amount, currency,
+// rollover и start_date; при ошибке ответ уже отправлен
+func applyBudgetFields(c fiber.Ctx, budget *models.Budget, data map[string]string) (bool, error) {
+	if data["currency"] != "" {
+		code, ok, err := parseCurrency(c, data["currency"])
+		if !ok {
+			return false, err
+		}
+		if data["amount"] == "" && currency.Round(budget.Amount, code) != budget.Amount {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid amount format",
+			})
+		}
+		budget.Currency = code
+	}
+	if data["amount"] != "" {
+		amount, ok, err := parseAmount(c, data["amount"], budget.Currency)
+		if !ok {
+			return false, err
+		}
+		budget.Amount = amount
+	}
+	if data["rollover"] != "" {
+		rollover, err := strconv.ParseBool(data["rollover"])
+		if err != nil {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid rollover value",
+			})
+		}
+		budget.Rollover = rollover
+	}
+	if data["start_date"] != "" {
+		parsedDate, err := time.Parse("2006-01-02", data["start_date"])
+		if err != nil {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		budget.StartDate = parsedDate
+	}
+	return true, nil
+}
+
+// findEditableBudget загружает бюджет из параметра id, который текущий пользователь
+// может изменять: личный или бюджет домохозяйства, где он владелец или редактор
+func findEditableBudget(c fiber.Ctx) (models.Budget, bool, error) {
+	var budget models.Budget
+	budgetId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return budget, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid budget ID",
+		})
+	}
+	err = database.DB.Where("id = ?", budgetId).
+		Scopes(households.VisibleBudgets(middleware.CurrentUserID(c))).First(&budget).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return budget, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Budget not found",
+			})
+		}
+		return budget, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	if budget.HouseholdID != nil {
+		if _, ok, err := authorizeHousehold(c, *budget.HouseholdID,
+			models.HouseholdRoleOwner, models.HouseholdRoleEditor); !ok {
+			return budget, false, err
+		}
+	}
+	return budget, true, nil
+}
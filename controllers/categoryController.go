@@ -3,19 +3,18 @@ package controllers
 import (
 	"github.com/gofiber/fiber/v3"
 	"project/database"
+	"project/households"
 	"project/logging"
+	"project/middleware"
 	"project/models"
 )
 
 func GetCategories(c fiber.Ctx) error {
 	logging.Logger.Info("Request to get categories")
 
-	id, err2, done := CheckUser(c)
-	if done {
-		return err2
-	}
+	id := middleware.CurrentUserID(c)
 	var categories []models.Category
-	database.DB.Where("owner_id =?", id).Or("owner_id = 0").Find(&categories)
+	database.DB.Scopes(households.VisibleCategories(id)).Find(&categories)
 
 	return c.JSON(categories)
 }
@@ -23,10 +22,7 @@ func GetCategories(c fiber.Ctx) error {
 func AddCategoryByUser(c fiber.Ctx) error {
 	logging.Logger.Info("Request to add category")
 
-	userId, err2, isCheck := CheckUser(c)
-	if isCheck {
-		return err2
-	}
+	userId := middleware.CurrentUserID(c)
 
 	var data map[string]string
 	if err := c.Bind().Body(&data); err != nil {
@@ -39,9 +35,14 @@ func AddCategoryByUser(c fiber.Ctx) error {
 			"error": "Missing required fields",
 		})
 	}
+	householdId, ok, err := householdFromValue(c, data["household_id"],
+		models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return err
+	}
 	existingCategory := models.Category{}
 	if err := database.DB.Where("name = ?", data["name"]).
-		Where("owner_id = ? OR owner_id = 0", userId).First(&existingCategory).Error; err == nil {
+		Scopes(households.UsableCategories(userId, householdId)).First(&existingCategory).Error; err == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Category already exists",
 		})
@@ -51,6 +52,7 @@ func AddCategoryByUser(c fiber.Ctx) error {
 	category.Name = data["name"]
 	category.Description = data["description"]
 	category.OwnerId = userId
+	category.HouseholdID = householdId
 	if err := database.DB.Create(&category).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create category",
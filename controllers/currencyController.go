@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"project/currency"
+	"project/logging"
+	"project/middleware"
+	"time"
+)
+
+// GetRates возвращает курсы валют за период (по умолчанию — за последние 30 дней)
+func GetRates(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get exchange rates")
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if value := c.Query("from"); value != "" {
+		parsedDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		from = parsedDate
+	}
+	if value := c.Query("to"); value != "" {
+		parsedDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		to = parsedDate
+	}
+
+	var codes [2]string
+	for i, value := range []string{c.Query("base"), c.Query("quote")} {
+		if value == "" {
+			continue
+		}
+		code, ok, err := parseCurrency(c, value)
+		if !ok {
+			return err
+		}
+		codes[i] = code
+	}
+
+	rates, err := currency.List(codes[0], codes[1], from, to)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving exchange rates",
+		})
+	}
+	return c.JSON(rates)
+}
+
+// AdminImportRates загружает курсы из тела запроса в формате CSV
+// "дата,базовая валюта,котируемая валюта,курс"
+func AdminImportRates(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to import exchange rates")
+
+	rates, err := currency.ParseCSV(bytes.NewReader(c.Body()), "manual")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid rates file",
+			"details": err.Error(),
+		})
+	}
+	if len(rates) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No rates to import",
+		})
+	}
+	if err := currency.Import(rates); err != nil {
+		logging.Logger.Error("Failed to import exchange rates:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import exchange rates",
+		})
+	}
+
+	logging.Logger.Info("Exchange rates imported",
+		zap.Int("rates", len(rates)),
+		zap.Uint("admin_id", middleware.CurrentUserID(c)),
+	)
+	return c.JSON(fiber.Map{
+		"imported": len(rates),
+	})
+}
+
+// AdminSyncRates немедленно загружает курсы из настроенного источника
+func AdminSyncRates(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to sync exchange rates")
+
+	imported, err := currency.Sync()
+	if err != nil {
+		if errors.Is(err, currency.ErrNoProvider) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Exchange rate provider is not configured",
+			})
+		}
+		logging.Logger.Error("Failed to sync exchange rates:", zap.Error(err))
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to sync exchange rates",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"imported": imported,
+	})
+}
+
+func AdminDeleteRate(c fiber.Ctx) error {
+	logging.Logger.Info("Admin request to delete exchange rate")
+
+	base, ok, err := parseCurrency(c, c.Params("base"))
+	if !ok {
+		return err
+	}
+	quote, ok, err := parseCurrency(c, c.Params("quote"))
+	if !ok {
+		return err
+	}
+	date, err := time.Parse("2006-01-02", c.Params("date"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date format",
+		})
+	}
+
+	deleted, err := currency.Delete(base, quote, date)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete exchange rate",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Exchange rate not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Exchange rate deleted successfully",
+	})
+}
+
+// reportCurrency возвращает валюту отчёта: параметр currency или базовую валюту пользователя
+func reportCurrency(c fiber.Ctx) (string, bool, error) {
+	if value := c.Query("currency"); value != "" {
+		return parseCurrency(c, value)
+	}
+	return middleware.CurrentUser(c).BaseCurrency, true, nil
+}
+
+// conversionError отвечает на ошибку пересчёта валют: отсутствие курса — ошибка
+// данных, о которой нужно сообщить клиенту, остальное — внутренняя ошибка
+func conversionError(c fiber.Ctx, err error, message string) error {
+	var missing *currency.MissingRateError
+	if errors.As(err, &missing) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Exchange rate not found",
+			"from":  missing.From,
+			"to":    missing.To,
+			"date":  missing.Date.Format("2006-01-02"),
+		})
+	}
+	logging.Logger.Error("Database error:", zap.Error(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
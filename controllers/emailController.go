@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"net/url"
+	"project/auth"
+	"project/config"
+	"project/logging"
+	"project/mail"
+	"project/middleware"
+	"project/models"
+)
+
+func VerifyEmail(c fiber.Ctx) error {
+	logging.Logger.Info("Received an email verification request")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["token"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	if _, err := auth.VerifyEmail(data["token"]); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid or expired verification token",
+			})
+		}
+		logging.Logger.Error("Failed to verify email:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified successfully",
+	})
+}
+
+func ResendVerificationEmail(c fiber.Ctx) error {
+	logging.Logger.Info("Request to resend verification email")
+
+	user := middleware.CurrentUser(c)
+	if user.EmailVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Email already verified",
+		})
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		if errors.Is(err, auth.ErrVerificationThrottled) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Verification email was sent recently, try again later",
+			})
+		}
+		logging.Logger.Error("Failed to send verification email:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to send email",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification email sent",
+	})
+}
+
+func sendVerificationEmail(user models.User) error {
+	if err := auth.ReserveVerificationEmail(user.ID); err != nil {
+		return err
+	}
+	token, err := auth.NewEmailVerificationToken(user)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.GetConfig().Server.PublicURL, url.QueryEscape(token))
+	return mail.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение адреса электронной почты",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить адрес электронной почты, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действительна %s.\n",
+			user.Username, link, config.GetConfig().EmailVerification.Expiration),
+	})
+}
@@ -3,34 +3,77 @@ package controllers
 import (
 	"errors"
 	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"project/budgets"
+	"project/currency"
 	"project/database"
+	"project/expenses"
+	"project/households"
 	"project/logging"
+	"project/middleware"
 	"project/models"
+	"project/money"
+	"project/splits"
 	"strconv"
+	"strings"
 	"time"
 )
 
 func GetExpenses(c fiber.Ctx) error {
 	logging.Logger.Info("Request to get expenses")
 
-	id, err2, done := CheckUser(c)
-	if done {
-		return err2
+	scope, ok, err := expenseScope(c)
+	if !ok {
+		return err
+	}
+	filter, ok, err := expenseFilter(c)
+	if !ok {
+		return err
+	}
+	sort, err := expenses.ParseSort(c.Query("sort"), c.Query("order"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sort parameters",
+		})
+	}
+	limit := expenses.DefaultLimit
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > expenses.MaxLimit {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit",
+			})
+		}
 	}
-	var expenses []models.Expense
-	database.DB.Where("user_id =?", id).Find(&expenses)
 
-	return c.JSON(expenses)
+	list, nextCursor, err := expenses.List(database.DB.Model(&models.Expense{}).Scopes(scope, filter.Scope),
+		sort, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, expenses.ErrInvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid cursor",
+			})
+		}
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving expenses",
+		})
+	}
+	response := fiber.Map{
+		"expenses":    list,
+		"next_cursor": nil,
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	return c.JSON(response)
 }
 
 func AddExpenseByUser(c fiber.Ctx) error {
 	logging.Logger.Info("Request to add expense")
 
-	userId, err2, isCheck := CheckUser(c)
-	if isCheck {
-		return err2
-	}
+	userId := middleware.CurrentUserID(c)
 
 	var data map[string]string
 	if err := c.Bind().Body(&data); err != nil {
@@ -44,8 +87,15 @@ func AddExpenseByUser(c fiber.Ctx) error {
 		})
 	}
 
+	householdId, ok, err := householdFromValue(c, data["household_id"],
+		models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return err
+	}
+
 	var category models.Category
-	if err := database.DB.Where("id = ?", data["category_id"]).Where("owner_id = ? OR owner_id = 0", userId).First(&category).Error; err != nil {
+	if err := database.DB.Where("id = ?", data["category_id"]).
+		Scopes(households.UsableCategories(userId, householdId)).First(&category).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Category not found",
@@ -59,13 +109,28 @@ func AddExpenseByUser(c fiber.Ctx) error {
 	var expense models.Expense
 	expense.Name = data["name"]
 	expense.UserID = userId
+	expense.HouseholdID = householdId
 	expense.CategoryID = category.ID
-	amountStr := data["amount"]
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid amount format",
-		})
+	expense.Currency = middleware.CurrentUser(c).BaseCurrency
+	if householdId != nil {
+		householdCurrency, err := households.Currency(*householdId)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		expense.Currency = householdCurrency
+	}
+	if data["currency"] != "" {
+		code, ok, err := parseCurrency(c, data["currency"])
+		if !ok {
+			return err
+		}
+		expense.Currency = code
+	}
+	amount, ok, err := parseAmount(c, data["amount"], expense.Currency)
+	if !ok {
+		return err
 	}
 	expense.Amount = amount
 	if dateStr, ok := data["date"]; ok && dateStr != "" {
@@ -77,7 +142,7 @@ func AddExpenseByUser(c fiber.Ctx) error {
 		}
 		expense.Date = parsedDate
 	} else {
-		expense.Date = time.Now()
+		expense.Date = expenses.Today(time.Now(), userLocation(c))
 	}
 
 	if err := database.DB.Create(&expense).Error; err != nil {
@@ -85,6 +150,7 @@ func AddExpenseByUser(c fiber.Ctx) error {
 			"error": "Failed to create expense",
 		})
 	}
+	checkBudgetAlerts(expense)
 
 	return c.JSON(expense)
 }
@@ -92,10 +158,6 @@ func AddExpenseByUser(c fiber.Ctx) error {
 func DeleteExpense(c fiber.Ctx) error {
 	logging.Logger.Info("Request to delete expense")
 
-	id, err2, done := CheckUser(c)
-	if done {
-		return err2
-	}
 	idStr := c.Params("id")
 	expenseId, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -103,18 +165,17 @@ func DeleteExpense(c fiber.Ctx) error {
 			"error": "Invalid expense ID",
 		})
 	}
-	var expense models.Expense
-	if err := database.DB.Where("id = ?", expenseId).Where("user_id = ?", id).First(&expense).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Expense not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Internal server error",
-		})
+	expense, ok, err := findEditableExpense(c, uint(expenseId))
+	if !ok {
+		return err
 	}
-	if err := database.DB.Delete(&expense).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := splits.DeleteShares(tx, []uint{expense.ID}); err != nil {
+			return err
+		}
+		return tx.Delete(&expense).Error
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete expense",
 		})
@@ -126,10 +187,7 @@ func DeleteExpense(c fiber.Ctx) error {
 
 func UpdateExpense(c fiber.Ctx) error {
 	logging.Logger.Info("Request to update expense")
-	id, err2, done := CheckUser(c)
-	if done {
-		return err2
-	}
+	id := middleware.CurrentUserID(c)
 	idStr := c.Params("id")
 	expenseId, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -137,16 +195,9 @@ func UpdateExpense(c fiber.Ctx) error {
 			"error": "Invalid expense ID",
 		})
 	}
-	var expense models.Expense
-	if err := database.DB.Where("id = ?", expenseId).Where("user_id = ?", id).First(&expense).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Expense not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Internal server error",
-		})
+	expense, ok, err := findEditableExpense(c, uint(expenseId))
+	if !ok {
+		return err
 	}
 	var data map[string]string
 	if err := c.Bind().Body(&data); err != nil {
@@ -159,7 +210,8 @@ func UpdateExpense(c fiber.Ctx) error {
 	}
 	if data["category_id"] != "" {
 		var category models.Category
-		if err := database.DB.Where("id = ?", data["category_id"]).Where("owner_id = ? OR owner_id = 0", id).First(&category).Error; err != nil {
+		if err := database.DB.Where("id = ?", data["category_id"]).
+			Scopes(households.UsableCategories(id, expense.HouseholdID)).First(&category).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 					"error": "Category not found",
@@ -171,14 +223,30 @@ func UpdateExpense(c fiber.Ctx) error {
 		}
 		expense.CategoryID = category.ID
 	}
-	if data["amount"] != "" {
-		amountStr := data["amount"]
-		amount, err := strconv.ParseFloat(amountStr, 64)
-		if err != nil {
+	if data["currency"] != "" {
+		code, ok, err := parseCurrency(c, data["currency"])
+		if !ok {
+			return err
+		}
+		if data["amount"] == "" && currency.Round(expense.Amount, code) != expense.Amount {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Invalid amount format",
 			})
 		}
+		expense.Currency = code
+	}
+	amountChanged := false
+	if data["amount"] != "" {
+		amount, ok, err := parseAmount(c, data["amount"], expense.Currency)
+		if !ok {
+			return err
+		}
+		if expense.SplitMethod == models.SplitExact && amount != expense.Amount {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Expense has exact split shares; remove the split before changing the amount",
+			})
+		}
+		amountChanged = amount != expense.Amount
 		expense.Amount = amount
 	}
 	if data["date"] != "" {
@@ -190,21 +258,27 @@ func UpdateExpense(c fiber.Ctx) error {
 		}
 		expense.Date = parsedDate
 	}
-	if err := database.DB.Save(&expense).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&expense).Error; err != nil {
+			return err
+		}
+		if !amountChanged {
+			return nil
+		}
+		return splits.Recalculate(tx, &expense)
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update expense",
 		})
 	}
+	checkBudgetAlerts(expense)
 	return c.JSON(expense)
 }
 
 func GetSumExpensesByCategoryId(c fiber.Ctx) error {
 	logging.Logger.Info("Request to get sum expenses by category")
 
-	id, err2, done := CheckUser(c)
-	if done {
-		return err2
-	}
 	idStr := c.Params("category_id")
 	categoryId, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -212,23 +286,273 @@ func GetSumExpensesByCategoryId(c fiber.Ctx) error {
 			"error": "Invalid category ID",
 		})
 	}
-	var sum float64
-	database.DB.Model(&models.Expense{}).Where("category_id = ?", categoryId).Where("user_id = ?", id).Select("SUM(amount)").Row().Scan(&sum)
+	scope, ok, err := expenseScope(c)
+	if !ok {
+		return err
+	}
+	target, ok, err := reportCurrency(c)
+	if !ok {
+		return err
+	}
+	period, ok, err := expensePeriod(c)
+	if !ok {
+		return err
+	}
+	sum, err := currency.NewConverter(target).
+		Total(database.DB.Model(&models.Expense{}).Where("category_id = ?", categoryId).Scopes(scope, period.Scope))
+	if err != nil {
+		return conversionError(c, err, "Internal server error")
+	}
 	return c.JSON(fiber.Map{
-		"sum": sum,
+		"sum":      sum,
+		"currency": target,
+		"period":   periodRange(period),
 	})
 }
 
 func GetSumExpenses(c fiber.Ctx) error {
 	logging.Logger.Info("Request to get sum expenses")
 
-	id, err2, done := CheckUser(c)
-	if done {
-		return err2
+	scope, ok, err := expenseScope(c)
+	if !ok {
+		return err
+	}
+	target, ok, err := reportCurrency(c)
+	if !ok {
+		return err
+	}
+	period, ok, err := expensePeriod(c)
+	if !ok {
+		return err
+	}
+	sum, err := currency.NewConverter(target).Total(database.DB.Model(&models.Expense{}).Scopes(scope, period.Scope))
+	if err != nil {
+		return conversionError(c, err, "Internal server error")
 	}
-	var sum float64
-	database.DB.Model(&models.Expense{}).Where("user_id = ?", id).Select("SUM(amount)").Row().Scan(&sum)
 	return c.JSON(fiber.Map{
-		"sum": sum,
+		"sum":      sum,
+		"currency": target,
+		"period":   periodRange(period),
 	})
 }
+
+// expenseScope выбирает расходы по параметру household_id: без него — все
+// доступные пользователю, "personal" — только личные, иначе — расходы
+// указанного домохозяйства, в котором пользователь состоит
+func expenseScope(c fiber.Ctx) (func(*gorm.DB) *gorm.DB, bool, error) {
+	userId := middleware.CurrentUserID(c)
+	switch value := c.Query("household_id"); value {
+	case "":
+		return households.VisibleExpenses(userId), true, nil
+	case "personal":
+		return households.PersonalExpenses(userId), true, nil
+	default:
+		householdId, ok, err := householdFromValue(c, value)
+		if !ok {
+			return nil, false, err
+		}
+		return households.HouseholdExpenses(*householdId), true, nil
+	}
+}
+
+// expenseFilter разбирает параметры фильтрации списка расходов: период (см.
+// expensePeriod), category_id (список через запятую), min_amount, max_amount и name
+func expenseFilter(c fiber.Ctx) (expenses.Filter, bool, error) {
+	filter, ok, err := expensePeriod(c)
+	if !ok {
+		return filter, false, err
+	}
+
+	if value := c.Query("category_id"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			categoryId, err := strconv.ParseUint(strings.TrimSpace(part), 10, 0)
+			if err != nil {
+				return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid category ID",
+				})
+			}
+			filter.CategoryIDs = append(filter.CategoryIDs, uint(categoryId))
+		}
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **money.Amount
+	}{{"min_amount", &filter.MinAmount}, {"max_amount", &filter.MaxAmount}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		amount, err := money.Parse(value)
+		if err != nil {
+			return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid amount format",
+			})
+		}
+		*param.target = &amount
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MaxAmount < *filter.MinAmount {
+		return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid amount range",
+		})
+	}
+
+	filter.Name = strings.TrimSpace(c.Query("name"))
+	return filter, true, nil
+}
+
+// expensePeriod разбирает период выборки: именованный период period или даты
+// from и to (YYYY-MM-DD) включительно; без параметров период не ограничен
+func expensePeriod(c fiber.Ctx) (expenses.Filter, bool, error) {
+	var filter expenses.Filter
+	if period := c.Query("period"); period != "" {
+		if c.Query("from") != "" || c.Query("to") != "" {
+			return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Use either period or from/to",
+			})
+		}
+		loc, ok, err := reportLocation(c)
+		if !ok {
+			return filter, false, err
+		}
+		from, to, err := expenses.ResolvePeriod(period, time.Now(), loc)
+		if err != nil {
+			return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown period",
+			})
+		}
+		filter.From, filter.To = &from, &to
+		return filter, true, nil
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		parsedDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		*param.target = &parsedDate
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date range",
+		})
+	}
+	return filter, true, nil
+}
+
+// periodRange возвращает границы периода для ответа; открытая граница — nil
+func periodRange(filter expenses.Filter) fiber.Map {
+	bounds := fiber.Map{"from": nil, "to": nil}
+	if filter.From != nil {
+		bounds["from"] = filter.From.Format("2006-01-02")
+	}
+	if filter.To != nil {
+		bounds["to"] = filter.To.Format("2006-01-02")
+	}
+	return bounds
+}
+
+// userLocation возвращает часовой пояс текущего пользователя
+func userLocation(c fiber.Ctx) *time.Location {
+	loc, err := time.LoadLocation(middleware.CurrentUser(c).Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// reportLocation возвращает часовой пояс отчёта: параметр timezone или часовой
+// пояс пользователя; при ошибке ответ уже отправлен
+func reportLocation(c fiber.Ctx) (*time.Location, bool, error) {
+	value := c.Query("timezone")
+	if value == "" {
+		return userLocation(c), true, nil
+	}
+	loc, err := time.LoadLocation(value)
+	if err != nil || value == "Local" {
+		return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown timezone",
+		})
+	}
+	return loc, true, nil
+}
+
+// checkBudgetAlerts в фоне проверяет пороги бюджетов, которые учитывают расход,
+// чтобы доставка уведомлений не задерживала ответ
+func checkBudgetAlerts(expense models.Expense) {
+	now := time.Now()
+	go func() {
+		if err := budgets.CheckAlerts(expense, now); err != nil {
+			logging.Logger.Error("Failed to check budget alerts", zap.Uint("expense_id", expense.ID), zap.Error(err))
+		}
+	}()
+}
+
+// findVisibleExpense загружает расход, который видит текущий пользователь
+func findVisibleExpense(c fiber.Ctx, expenseId uint) (models.Expense, bool, error) {
+	userId := middleware.CurrentUserID(c)
+	var expense models.Expense
+	if err := database.DB.Where("id = ?", expenseId).Scopes(households.VisibleExpenses(userId)).First(&expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return expense, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Expense not found",
+			})
+		}
+		return expense, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return expense, true, nil
+}
+
+// findEditableExpense загружает расход, который текущий пользователь может изменять.
+// Участник домохозяйства с ролью viewer видит его расходы, но изменять их не может
+func findEditableExpense(c fiber.Ctx, expenseId uint) (models.Expense, bool, error) {
+	expense, ok, err := findVisibleExpense(c, expenseId)
+	if !ok {
+		return expense, false, err
+	}
+	if expense.HouseholdID != nil {
+		if _, ok, err := authorizeHousehold(c, *expense.HouseholdID,
+			models.HouseholdRoleOwner, models.HouseholdRoleEditor); !ok {
+			return expense, false, err
+		}
+	}
+	return expense, true, nil
+}
+
+// parseAmount строго разбирает положительную сумму в валюте; при ошибке ответ уже отправлен
+func parseAmount(c fiber.Ctx, value, code string) (money.Amount, bool, error) {
+	amount, err := currency.ParseAmount(value, code)
+	if err != nil {
+		if errors.Is(err, money.ErrNonPositive) {
+			return 0, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Amount must be positive",
+			})
+		}
+		return 0, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid amount format",
+		})
+	}
+	return amount, true, nil
+}
+
+// parseCurrency проверяет код валюты ISO 4217; при ошибке ответ уже отправлен
+func parseCurrency(c fiber.Ctx, value string) (string, bool, error) {
+	code, err := currency.Normalize(value)
+	if err != nil {
+		return "", false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unsupported currency",
+		})
+	}
+	return code, true, nil
+}
@@ -0,0 +1,484 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"net/url"
+	"project/config"
+	"project/currency"
+	"project/database"
+	"project/households"
+	"project/logging"
+	"project/mail"
+	"project/middleware"
+	"project/models"
+	"strconv"
+	"strings"
+)
+
+func GetHouseholds(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get households")
+
+	memberships, err := households.ListForUser(middleware.CurrentUserID(c))
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving households",
+		})
+	}
+
+	response := make([]fiber.Map, 0, len(memberships))
+	for _, membership := range memberships {
+		response = append(response, fiber.Map{
+			"household_id": membership.HouseholdID,
+			"name":         membership.Household.Name,
+			"currency":     membership.Household.Currency,
+			"role":         membership.Role,
+			"joined_at":    membership.CreatedAt,
+		})
+	}
+	return c.JSON(response)
+}
+
+func CreateHousehold(c fiber.Ctx) error {
+	logging.Logger.Info("Request to create household")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	name := strings.TrimSpace(data["name"])
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	householdCurrency := middleware.CurrentUser(c).BaseCurrency
+	if data["currency"] != "" {
+		code, err := currency.Normalize(data["currency"])
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unsupported currency",
+			})
+		}
+		householdCurrency = code
+	}
+
+	household, err := households.Create(middleware.CurrentUserID(c), name, householdCurrency)
+	if err != nil {
+		logging.Logger.Error("Failed to create household:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create household",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(household)
+}
+
+func GetHousehold(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get household")
+
+	householdId, role, ok, err := householdFromParam(c)
+	if !ok {
+		return err
+	}
+
+	var household models.Household
+	if err := database.DB.First(&household, householdId).Error; err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving household",
+		})
+	}
+	members, err := households.Members(householdId)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving household",
+		})
+	}
+
+	memberList := make([]fiber.Map, 0, len(members))
+	for _, member := range members {
+		memberList = append(memberList, fiber.Map{
+			"user_id":   member.UserID,
+			"username":  member.User.Username,
+			"role":      member.Role,
+			"joined_at": member.CreatedAt,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"household_id": household.ID,
+		"name":         household.Name,
+		"currency":     household.Currency,
+		"created_at":   household.CreatedAt,
+		"role":         role,
+		"members":      memberList,
+	})
+}
+
+func UpdateHousehold(c fiber.Ctx) error {
+	logging.Logger.Info("Request to update household")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner)
+	if !ok {
+		return err
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	updates := map[string]interface{}{}
+	if name := strings.TrimSpace(data["name"]); name != "" {
+		updates["name"] = name
+	}
+	if data["currency"] != "" {
+		code, err := currency.Normalize(data["currency"])
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unsupported currency",
+			})
+		}
+		updates["currency"] = code
+	}
+	if len(updates) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	household := models.Household{ID: householdId}
+	if err := database.DB.Model(&household).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update household",
+		})
+	}
+	if err := database.DB.First(&household).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(household)
+}
+
+func DeleteHousehold(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete household")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner)
+	if !ok {
+		return err
+	}
+
+	if err := households.Delete(householdId); err != nil {
+		if errors.Is(err, households.ErrHouseholdNotEmpty) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Household still has expenses",
+			})
+		}
+		logging.Logger.Error("Failed to delete household:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete household",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Household deleted successfully",
+	})
+}
+
+func CreateHouseholdInvitation(c fiber.Ctx) error {
+	logging.Logger.Info("Request to create household invitation")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner)
+	if !ok {
+		return err
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	role := data["role"]
+	if role == "" {
+		role = models.HouseholdRoleEditor
+	}
+	if data["email"] != "" && !isValidEmail(data["email"]) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email format",
+		})
+	}
+
+	token, invitation, err := households.CreateInvitation(householdId, middleware.CurrentUserID(c), role)
+	if err != nil {
+		if errors.Is(err, households.ErrInvalidRole) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid role",
+			})
+		}
+		logging.Logger.Error("Failed to create household invitation:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create invitation",
+		})
+	}
+
+	link := fmt.Sprintf("%s/households/join?token=%s", config.GetConfig().Server.PublicURL, url.QueryEscape(token))
+	if data["email"] != "" {
+		var household models.Household
+		database.DB.First(&household, householdId)
+		err := mail.Send(mail.Message{
+			To:      data["email"],
+			Subject: "Приглашение в общий бюджет",
+			Body: fmt.Sprintf("Здравствуйте!\n\n%s приглашает вас вести общий бюджет «%s».\n"+
+				"Чтобы присоединиться, перейдите по ссылке:\n%s\n\nСсылка действительна до %s.\n",
+				middleware.CurrentUser(c).Username, household.Name, link, invitation.ExpiresAt.Format("02.01.2006 15:04")),
+		})
+		if err != nil {
+			logging.Logger.Error("Failed to send invitation email:", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to send email",
+			})
+		}
+	}
+
+	logging.Logger.Info("Household invitation created",
+		zap.Uint("household_id", householdId),
+		zap.Uint("invitation_id", invitation.ID),
+	)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"invitation_id": invitation.ID,
+		"role":          invitation.Role,
+		"token":         token,
+		"link":          link,
+		"expires_at":    invitation.ExpiresAt,
+	})
+}
+
+func GetHouseholdInvitations(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get household invitations")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner)
+	if !ok {
+		return err
+	}
+
+	invitations, err := households.PendingInvitations(householdId)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving invitations",
+		})
+	}
+	return c.JSON(invitations)
+}
+
+func DeleteHouseholdInvitation(c fiber.Ctx) error {
+	logging.Logger.Info("Request to revoke household invitation")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner)
+	if !ok {
+		return err
+	}
+	invitationId, err := strconv.ParseUint(c.Params("invitation_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid invitation ID",
+		})
+	}
+
+	revoked, err := households.RevokeInvitation(householdId, uint(invitationId))
+	if err != nil {
+		logging.Logger.Error("Failed to revoke invitation:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke invitation",
+		})
+	}
+	if !revoked {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invitation not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Invitation revoked successfully",
+	})
+}
+
+func AcceptHouseholdInvitation(c fiber.Ctx) error {
+	logging.Logger.Info("Request to accept household invitation")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["token"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	member, err := households.AcceptInvitation(data["token"], middleware.CurrentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, households.ErrInvalidInvitation):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid or expired invitation",
+			})
+		case errors.Is(err, households.ErrAlreadyMember):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Already a household member",
+			})
+		}
+		logging.Logger.Error("Failed to accept invitation:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to accept invitation",
+		})
+	}
+
+	logging.Logger.Info("Household invitation accepted",
+		zap.Uint("household_id", member.HouseholdID),
+		zap.Uint("user_id", member.UserID),
+	)
+	return c.JSON(member)
+}
+
+func UpdateHouseholdMember(c fiber.Ctx) error {
+	logging.Logger.Info("Request to change household member role")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner)
+	if !ok {
+		return err
+	}
+	memberId, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	if err := households.SetMemberRole(householdId, uint(memberId), data["role"]); err != nil {
+		return householdMemberError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Member role updated successfully",
+	})
+}
+
+// DeleteHouseholdMember исключает участника; владелец может исключить любого,
+// остальные участники — только выйти сами
+func DeleteHouseholdMember(c fiber.Ctx) error {
+	logging.Logger.Info("Request to remove household member")
+
+	memberId, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	var roles []string
+	if uint(memberId) != middleware.CurrentUserID(c) {
+		roles = []string{models.HouseholdRoleOwner}
+	}
+	householdId, _, ok, err := householdFromParam(c, roles...)
+	if !ok {
+		return err
+	}
+
+	if err := households.RemoveMember(householdId, uint(memberId)); err != nil {
+		return householdMemberError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Member removed successfully",
+	})
+}
+
+// householdFromParam разбирает параметр :id и проверяет роль текущего пользователя
+// в домохозяйстве; при отказе ответ уже отправлен
+func householdFromParam(c fiber.Ctx, roles ...string) (uint, string, bool, error) {
+	householdId, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return 0, "", false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid household ID",
+		})
+	}
+	role, ok, err := authorizeHousehold(c, uint(householdId), roles...)
+	return uint(householdId), role, ok, err
+}
+
+// householdFromValue разбирает необязательный идентификатор домохозяйства из тела
+// или строки запроса; пустое значение означает личную запись
+func householdFromValue(c fiber.Ctx, value string, roles ...string) (*uint, bool, error) {
+	if value == "" {
+		return nil, true, nil
+	}
+	householdId, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid household ID",
+		})
+	}
+	id := uint(householdId)
+	if _, ok, err := authorizeHousehold(c, id, roles...); !ok {
+		return nil, false, err
+	}
+	return &id, true, nil
+}
+
+// authorizeHousehold проверяет, что текущий пользователь состоит в домохозяйстве
+// с одной из ролей. Чужие домохозяйства неотличимы от несуществующих
+func authorizeHousehold(c fiber.Ctx, householdId uint, roles ...string) (string, bool, error) {
+	role, err := households.Authorize(householdId, middleware.CurrentUserID(c), roles...)
+	if err != nil {
+		switch {
+		case errors.Is(err, households.ErrNotMember):
+			return "", false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Household not found",
+			})
+		case errors.Is(err, households.ErrInsufficientRole):
+			return "", false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient household role",
+			})
+		}
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return "", false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return role, true, nil
+}
+
+func householdMemberError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, households.ErrInvalidRole):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role",
+		})
+	case errors.Is(err, households.ErrNotMember):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Member not found",
+		})
+	case errors.Is(err, households.ErrLastOwner):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Household must keep at least one owner",
+		})
+	}
+	logging.Logger.Error("Failed to update household member:", zap.Error(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to update household member",
+	})
+}
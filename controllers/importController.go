@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"io"
+	"project/households"
+	"project/imports"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// ImportExpenses импортирует расходы из CSV-файла (multipart, поле file).
+// Формат задаётся полями delimiter, decimal_separator, thousands_separator,
+// date_format (можно повторять), has_header и <поле>_column. С dry_run=true
+// файл только проверяется; иначе расходы записываются, только если ошибок нет
+func ImportExpenses(c fiber.Ctx) error {
+	logging.Logger.Info("Request to import expenses")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "File is required",
+		})
+	}
+	options, ok, err := csvOptions(c)
+	if !ok {
+		return err
+	}
+	dryRun, ok, err := importDryRun(c)
+	if !ok {
+		return err
+	}
+	target, ok, err := importTarget(c)
+	if !ok {
+		return err
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logging.Logger.Error("Failed to open uploaded file", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
+	}
+	defer file.Close()
+
+	result, err := imports.ParseCSV(file, options, target)
+	if err != nil {
+		if errors.Is(err, imports.ErrTooManyRows) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "Too many rows in file",
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid CSV file",
+			"details": err.Error(),
+		})
+	}
+
+	if dryRun {
+		return c.JSON(fiber.Map{
+			"dry_run":  true,
+			"rows":     result.Rows,
+			"valid":    len(result.Expenses),
+			"errors":   result.Errors,
+			"expenses": result.Expenses,
+		})
+	}
+	if len(result.Errors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "File contains invalid rows; nothing was imported",
+			"rows":   result.Rows,
+			"errors": result.Errors,
+		})
+	}
+	if len(result.Expenses) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No expenses to import",
+		})
+	}
+
+	imported, err := imports.Commit(result.Expenses, time.Now())
+	if err != nil {
+		logging.Logger.Error("Failed to import expenses:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import expenses",
+		})
+	}
+	logging.Logger.Info("Expenses imported",
+		zap.Int("expenses", imported),
+		zap.Uint("user_id", middleware.CurrentUserID(c)),
+	)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"imported": imported,
+	})
+}
+
+// ImportStatement импортирует списания из банковской выписки OFX, QIF или CAMT.053
+// (multipart, поле file). Формат определяется по содержимому, если не задан в format.
+// Операции, импортированные ранее из этой или пересекающейся выписки, пропускаются.
+// Для QIF формат дат и сумм задаётся полями date_format и decimal_separator
+func ImportStatement(c fiber.Ctx) error {
+	logging.Logger.Info("Request to import bank statement")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "File is required",
+		})
+	}
+	options, ok, err := statementOptions(c)
+	if !ok {
+		return err
+	}
+	dryRun, ok, err := importDryRun(c)
+	if !ok {
+		return err
+	}
+	target, ok, err := importTarget(c)
+	if !ok {
+		return err
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logging.Logger.Error("Failed to open uploaded file", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		logging.Logger.Error("Failed to read uploaded file", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format, err = imports.DetectFormat(data)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown statement format",
+			})
+		}
+	}
+	transactions, err := imports.ParseStatement(format, data, options)
+	if err != nil {
+		if errors.Is(err, imports.ErrUnknownFormat) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown statement format",
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid statement file",
+			"details": err.Error(),
+		})
+	}
+	result, err := imports.BuildStatement(format, transactions, target)
+	if err != nil {
+		if errors.Is(err, imports.ErrTooManyRows) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "Too many rows in file",
+			})
+		}
+		logging.Logger.Error("Failed to prepare statement import:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import statement",
+		})
+	}
+
+	if dryRun {
+		return c.JSON(fiber.Map{
+			"dry_run":    true,
+			"format":     format,
+			"rows":       result.Rows,
+			"valid":      len(result.Expenses),
+			"skipped":    result.Skipped,
+			"duplicates": result.Duplicates,
+			"errors":     result.Errors,
+			"expenses":   result.Expenses,
+		})
+	}
+	if len(result.Errors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Statement contains invalid transactions; nothing was imported",
+			"rows":   result.Rows,
+			"errors": result.Errors,
+		})
+	}
+
+	imported, err := imports.Commit(result.Expenses, time.Now())
+	if err != nil {
+		logging.Logger.Error("Failed to import statement:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import statement",
+		})
+	}
+	logging.Logger.Info("Bank statement imported",
+		zap.String("format", format),
+		zap.Int("expenses", imported),
+		zap.Uint("user_id", middleware.CurrentUserID(c)),
+	)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"imported":   imported,
+		"skipped":    result.Skipped,
+		"duplicates": result.Duplicates + len(result.Expenses) - imported,
+	})
+}
+
+// importDryRun читает поле dry_run; при ошибке ответ уже отправлен
+func importDryRun(c fiber.Ctx) (bool, bool, error) {
+	value := c.FormValue("dry_run")
+	if value == "" {
+		return false, true, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dry_run value",
+		})
+	}
+	return dryRun, true, nil
+}
+
+// importTarget определяет владельца импортируемых расходов по полям household_id,
+// currency и default_category_id; при ошибке ответ уже отправлен
+func importTarget(c fiber.Ctx) (imports.Target, bool, error) {
+	target := imports.Target{
+		UserID:   middleware.CurrentUserID(c),
+		Currency: middleware.CurrentUser(c).BaseCurrency,
+	}
+	householdId, ok, err := householdFromValue(c, c.FormValue("household_id"),
+		models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return target, false, err
+	}
+	target.HouseholdID = householdId
+	if householdId != nil {
+		householdCurrency, err := households.Currency(*householdId)
+		if err != nil {
+			return target, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		target.Currency = householdCurrency
+	}
+	if value := c.FormValue("currency"); value != "" {
+		code, ok, err := parseCurrency(c, value)
+		if !ok {
+			return target, false, err
+		}
+		target.Currency = code
+	}
+	if value := c.FormValue("default_category_id"); value != "" {
+		category, ok, err := usableCategory(c, value, householdId)
+		if !ok {
+			return target, false, err
+		}
+		target.DefaultCategoryID = &category.ID
+	}
+	return target, true, nil
+}
+
+// statementOptions читает формат дат и сумм для QIF; при ошибке ответ уже отправлен
+func statementOptions(c fiber.Ctx) (imports.StatementOptions, bool, error) {
+	options := imports.DefaultStatementOptions()
+	if value := c.FormValue("decimal_separator"); value != "" {
+		if value != "." && value != "," {
+			return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid decimal separator",
+			})
+		}
+		options.DecimalSeparator = rune(value[0])
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse form",
+		})
+	}
+	if formats := form.Value["date_format"]; len(formats) > 0 {
+		options.DateFormats = formats
+	}
+	return options, true, nil
+}
+
+// csvOptions читает формат файла из полей формы; при ошибке ответ уже отправлен
+func csvOptions(c fiber.Ctx) (imports.CSVOptions, bool, error) {
+	options := imports.DefaultCSVOptions()
+
+	if value := c.FormValue("delimiter"); value != "" {
+		if value == `\t` || value == "tab" {
+			value = "\t"
+		}
+		delimiter, ok := singleRune(value)
+		if !ok || delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+			return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid delimiter",
+			})
+		}
+		options.Delimiter = delimiter
+	}
+	if value := c.FormValue("decimal_separator"); value != "" {
+		if value != "." && value != "," {
+			return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid decimal separator",
+			})
+		}
+		options.DecimalSeparator = rune(value[0])
+	}
+	if value := c.FormValue("thousands_separator"); value != "" {
+		separator, ok := singleRune(value)
+		if !ok || separator == options.DecimalSeparator || separator >= '0' && separator <= '9' {
+			return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid thousands separator",
+			})
+		}
+		options.ThousandsSeparator = separator
+	}
+	if value := c.FormValue("has_header"); value != "" {
+		hasHeader, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid has_header value",
+			})
+		}
+		options.HasHeader = hasHeader
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return options, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse form",
+		})
+	}
+	if formats := form.Value["date_format"]; len(formats) > 0 {
+		options.DateFormats = formats
+	}
+	for _, field := range imports.Fields {
+		if values, ok := form.Value[field+"_column"]; ok {
+			options.Columns[field] = values[0]
+		}
+	}
+	// Без заголовка столбцы можно указать только номерами
+	if !options.HasHeader {
+		for field, column := range options.Columns {
+			if column == field {
+				delete(options.Columns, field)
+			}
+		}
+	}
+	return options, true, nil
+}
+
+func singleRune(value string) (rune, bool) {
+	r, size := utf8.DecodeRuneInString(value)
+	return r, r != utf8.RuneError && size == len(value)
+}
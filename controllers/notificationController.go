@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"net/url"
+	"project/logging"
+	"project/middleware"
+	"project/notifications"
+	"strconv"
+)
+
+// GetNotifications возвращает последние уведомления; с unread=true — только непрочитанные
+func GetNotifications(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get notifications")
+
+	unreadOnly := false
+	if value := c.Query("unread"); value != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid unread value",
+			})
+		}
+	}
+	list, err := notifications.Inbox(middleware.CurrentUserID(c), unreadOnly, 100)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving notifications",
+		})
+	}
+	return c.JSON(list)
+}
+
+func MarkNotificationRead(c fiber.Ctx) error {
+	logging.Logger.Info("Request to mark notification as read")
+
+	notificationId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid notification ID",
+		})
+	}
+	id := uint(notificationId)
+	updated, err := notifications.MarkRead(middleware.CurrentUserID(c), &id)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notification",
+		})
+	}
+	if updated == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Unread notification not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Notification marked as read",
+	})
+}
+
+func MarkAllNotificationsRead(c fiber.Ctx) error {
+	logging.Logger.Info("Request to mark all notifications as read")
+
+	updated, err := notifications.MarkRead(middleware.CurrentUserID(c), nil)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notifications",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Notifications marked as read",
+		"updated": updated,
+	})
+}
+
+// GetNotificationPreferences возвращает каналы доставки каждого вида уведомлений
+func GetNotificationPreferences(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get notification preferences")
+
+	preferences, err := notifications.Preferences(middleware.CurrentUserID(c))
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving notification preferences",
+		})
+	}
+	return c.JSON(preferences)
+}
+
+// UpdateNotificationPreference задаёт каналы одного вида уведомлений;
+// пустой список channels отключает уведомления этого вида
+func UpdateNotificationPreference(c fiber.Ctx) error {
+	logging.Logger.Info("Request to update notification preference")
+
+	var data struct {
+		Kind     string   `json:"kind"`
+		Channels []string `json:"channels"`
+	}
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data.Kind == "" || data.Channels == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	if err := notifications.SetPreference(middleware.CurrentUserID(c), data.Kind, data.Channels); err != nil {
+		switch {
+		case errors.Is(err, notifications.ErrUnknownKind):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown notification kind",
+			})
+		case errors.Is(err, notifications.ErrUnknownChannel):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown notification channel",
+			})
+		}
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notification preference",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"kind":     data.Kind,
+		"channels": data.Channels,
+	})
+}
+
+// SetNotificationWebhook задаёт адрес вебхука. Ключ подписи возвращается только
+// в этом ответе; повторный вызов выпускает новый ключ
+func SetNotificationWebhook(c fiber.Ctx) error {
+	logging.Logger.Info("Request to set notification webhook")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	parsed, err := url.Parse(data["url"])
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook URL",
+		})
+	}
+
+	webhook, secret, err := notifications.SetWebhook(middleware.CurrentUserID(c), parsed.String())
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set webhook",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"url":        webhook.URL,
+		"secret":     secret,
+		"created_at": webhook.CreatedAt,
+	})
+}
+
+func DeleteNotificationWebhook(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete notification webhook")
+
+	deleted, err := notifications.DeleteWebhook(middleware.CurrentUserID(c))
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Webhook deleted successfully",
+	})
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"project/account"
+	"project/logging"
+	"project/middleware"
+	"project/oidc"
+	"time"
+)
+
+func OIDCLogin(c fiber.Ctx) error {
+	provider := c.Params("provider")
+	logging.Logger.Info("Received an OIDC login request", zap.String("provider", provider))
+
+	authURL, state, err := oidc.BeginLogin(c.UserContext(), provider)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Unknown provider",
+			})
+		}
+		logging.Logger.Error("Failed to start OIDC login:", zap.Error(err))
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Identity provider is unavailable",
+		})
+	}
+
+	// state дублируется в cookie, чтобы завершить вход мог только начавший его браузер
+	c.Cookie(&fiber.Cookie{
+		Name:     "oidc_state",
+		Value:    state,
+		Path:     "/api/oidc",
+		Expires:  time.Now().Add(10 * time.Minute),
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect().To(authURL)
+}
+
+func OIDCCallback(c fiber.Ctx) error {
+	provider := c.Params("provider")
+	logging.Logger.Info("Received an OIDC callback", zap.String("provider", provider))
+
+	if providerError := c.Query("error"); providerError != "" {
+		logging.Logger.Warn("OIDC provider returned an error", zap.String("error", providerError))
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authentication was rejected by identity provider",
+		})
+	}
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	if c.Cookies("oidc_state") != state {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid state",
+		})
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     "oidc_state",
+		Value:    "",
+		Path:     "/api/oidc",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Secure:   true,
+	})
+
+	claims, err := oidc.CompleteLogin(c.UserContext(), provider, state, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrUnknownProvider):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Unknown provider",
+			})
+		case errors.Is(err, oidc.ErrInvalidState):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid state",
+			})
+		}
+		logging.Logger.Error("Failed to complete OIDC login:", zap.Error(err))
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Failed to authenticate with identity provider",
+		})
+	}
+
+	user, err := account.FindOrCreateOIDCUser(provider, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, account.ErrOIDCEmailRequired):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Identity provider did not return an email",
+			})
+		case errors.Is(err, account.ErrOIDCEmailNotVerified):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "An account with this email already exists, log in with password",
+			})
+		}
+		logging.Logger.Error("Failed to link OIDC identity:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return loginOrChallenge(c, *user)
+}
+
+func GetIdentities(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get linked identities")
+
+	identities, err := account.ListIdentities(middleware.CurrentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(identities)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/url"
+	"project/auth"
+	"project/config"
+	"project/database"
+	"project/logging"
+	"project/mail"
+	"project/models"
+)
+
+func ForgotPassword(c fiber.Ctx) error {
+	logging.Logger.Info("Received a forgot password request")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["email"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	// Ответ не зависит от существования аккаунта, чтобы по нему нельзя было перебирать email
+	response := fiber.Map{
+		"message": "If the account exists, a password reset link has been sent",
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", data["email"]).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logging.Logger.Info("Password reset requested for unknown email")
+			return c.JSON(response)
+		}
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	// Письмо отправляется в фоне: по времени ответа тоже нельзя узнать, есть ли аккаунт
+	go func() {
+		if err := sendPasswordResetEmail(user); err != nil {
+			logging.Logger.Error("Failed to send reset email:", zap.Error(err), zap.Uint("user_id", user.ID))
+		}
+	}()
+
+	return c.JSON(response)
+}
+
+// sendPasswordResetEmail создаёт токен сброса пароля и отправляет ссылку на него
+func sendPasswordResetEmail(user models.User) error {
+	token, err := auth.CreatePasswordResetToken(user.ID)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.GetConfig().Server.PublicURL, url.QueryEscape(token))
+	return mail.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действительна %s. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Username, link, config.GetConfig().PasswordReset.Expiration),
+	})
+}
+
+func ResetPassword(c fiber.Ctx) error {
+	logging.Logger.Info("Received a reset password request")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["token"] == "" || data["password"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	if err := auth.ResetPassword(data["token"], data["password"]); err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid or expired reset token",
+			})
+		}
+		logging.Logger.Error("Failed to reset password:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset password",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password reset successfully",
+	})
+}
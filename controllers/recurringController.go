@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/currency"
+	"project/database"
+	"project/expenses"
+	"project/households"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"project/recurring"
+	"strconv"
+	"time"
+)
+
+func GetRecurringExpenses(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get recurring expenses")
+
+	var rules []models.RecurringExpense
+	err := database.DB.Scopes(households.VisibleRecurringExpenses(middleware.CurrentUserID(c))).
+		Order("id").Find(&rules).Error
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving recurring expenses",
+		})
+	}
+	return c.JSON(rules)
+}
+
+// CreateRecurringExpense создаёт правило повторения. Повторения, наступившие
+// с даты начала, создаются сразу
+func CreateRecurringExpense(c fiber.Ctx) error {
+	logging.Logger.Info("Request to create recurring expense")
+
+	userId := middleware.CurrentUserID(c)
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["name"] == "" || data["category_id"] == "" || data["amount"] == "" ||
+		data["frequency"] == "" || data["start_date"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	householdId, ok, err := householdFromValue(c, data["household_id"],
+		models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return err
+	}
+
+	rule := models.RecurringExpense{
+		UserID:      userId,
+		HouseholdID: householdId,
+		Name:        data["name"],
+		Currency:    middleware.CurrentUser(c).BaseCurrency,
+		Interval:    1,
+	}
+	if householdId != nil {
+		householdCurrency, err := households.Currency(*householdId)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		rule.Currency = householdCurrency
+	}
+	if ok, err := applyRecurringFields(c, &rule, data); !ok {
+		return err
+	}
+	recurring.Reset(&rule, rule.StartDate)
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create recurring expense",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(materializeRecurring(rule))
+}
+
+// UpdateRecurringExpense изменяет правило. Новое расписание действует с сегодняшнего
+// дня: созданные расходы остаются, а пропуски и изменения повторений сбрасываются
+func UpdateRecurringExpense(c fiber.Ctx) error {
+	logging.Logger.Info("Request to update recurring expense")
+
+	rule, ok, err := findEditableRecurring(c)
+	if !ok {
+		return err
+	}
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["name"] != "" {
+		rule.Name = data["name"]
+	}
+	frequency, interval, startDate := rule.Frequency, rule.Interval, rule.StartDate
+	if ok, err := applyRecurringFields(c, &rule, data); !ok {
+		return err
+	}
+	_, endDateSet := data["end_date"]
+	scheduleChanged := rule.Frequency != frequency || rule.Interval != interval ||
+		!rule.StartDate.Equal(startDate) || endDateSet
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if scheduleChanged {
+			from := expenses.Today(time.Now(), userLocation(c))
+			if rule.StartDate.After(from) {
+				from = rule.StartDate
+			}
+			recurring.Reset(&rule, from)
+			if err := recurring.ResetExceptions(tx, []uint{rule.ID}); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&rule).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update recurring expense",
+		})
+	}
+	return c.JSON(materializeRecurring(rule))
+}
+
+// DeleteRecurringExpense удаляет правило; уже созданные расходы остаются
+func DeleteRecurringExpense(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete recurring expense")
+
+	rule, ok, err := findEditableRecurring(c)
+	if !ok {
+		return err
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := recurring.ResetExceptions(tx, []uint{rule.ID}); err != nil {
+			return err
+		}
+		return tx.Delete(&rule).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete recurring expense",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Recurring expense deleted successfully",
+	})
+}
+
+// GetRecurringOccurrences возвращает ближайшие несозданные повторения (count, по умолчанию 10)
+func GetRecurringOccurrences(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get recurring expense occurrences")
+
+	rule, ok, err := findRecurring(c)
+	if !ok {
+		return err
+	}
+	count := 10
+	if value := c.Query("count"); value != "" {
+		count, err = strconv.Atoi(value)
+		if err != nil || count < 1 || count > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid count",
+			})
+		}
+	}
+	upcoming, err := recurring.Upcoming(rule, count)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving occurrences",
+		})
+	}
+	return c.JSON(upcoming)
+}
+
+// SetRecurringOccurrence пропускает (skip) или изменяет название, сумму или
+// категорию одного ещё не созданного повторения
+func SetRecurringOccurrence(c fiber.Ctx) error {
+	logging.Logger.Info("Request to change recurring expense occurrence")
+
+	rule, ok, err := findEditableRecurring(c)
+	if !ok {
+		return err
+	}
+	date, err := time.Parse("2006-01-02", c.Params("date"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date format",
+		})
+	}
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	exception := models.RecurringException{Date: date, Name: data["name"]}
+	if data["skip"] != "" {
+		exception.Skip, err = strconv.ParseBool(data["skip"])
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid skip value",
+			})
+		}
+	}
+	if data["amount"] != "" {
+		amount, ok, err := parseAmount(c, data["amount"], rule.Currency)
+		if !ok {
+			return err
+		}
+		exception.Amount = &amount
+	}
+	if data["category_id"] != "" {
+		category, ok, err := usableCategory(c, data["category_id"], rule.HouseholdID)
+		if !ok {
+			return err
+		}
+		exception.CategoryID = &category.ID
+	}
+	if !exception.Skip && exception.Name == "" && exception.Amount == nil && exception.CategoryID == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	if err := recurring.SetException(rule, exception); err != nil {
+		switch {
+		case errors.Is(err, recurring.ErrNotOccurrence):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Date is not an occurrence of this recurring expense",
+			})
+		case errors.Is(err, recurring.ErrAlreadyGenerated):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Occurrence has already been created; edit the expense instead",
+			})
+		}
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change occurrence",
+		})
+	}
+	return c.JSON(exception)
+}
+
+// DeleteRecurringOccurrence возвращает повторению значения из правила
+func DeleteRecurringOccurrence(c fiber.Ctx) error {
+	logging.Logger.Info("Request to reset recurring expense occurrence")
+
+	rule, ok, err := findEditableRecurring(c)
+	if !ok {
+		return err
+	}
+	date, err := time.Parse("2006-01-02", c.Params("date"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date format",
+		})
+	}
+	deleted, err := recurring.DeleteException(rule.ID, date)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset occurrence",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Occurrence change not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Occurrence reset successfully",
+	})
+}
+
+// applyRecurringFields применяет к правилу поля category_id, currency, amount,
+// frequency, interval, start_date и end_date (пустое значение снимает дату
+// окончания); при ошибке ответ уже отправлен
+func applyRecurringFields(c fiber.Ctx, rule *models.RecurringExpense, data map[string]string) (bool, error) {
+	if data["category_id"] != "" {
+		category, ok, err := usableCategory(c, data["category_id"], rule.HouseholdID)
+		if !ok {
+			return false, err
+		}
+		rule.CategoryID = category.ID
+	}
+	if data["currency"] != "" {
+		code, ok, err := parseCurrency(c, data["currency"])
+		if !ok {
+			return false, err
+		}
+		if data["amount"] == "" && currency.Round(rule.Amount, code) != rule.Amount {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid amount format",
+			})
+		}
+		rule.Currency = code
+	}
+	if data["amount"] != "" {
+		amount, ok, err := parseAmount(c, data["amount"], rule.Currency)
+		if !ok {
+			return false, err
+		}
+		rule.Amount = amount
+	}
+	if data["frequency"] != "" {
+		if !recurring.IsValidFrequency(data["frequency"]) {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid frequency",
+			})
+		}
+		rule.Frequency = data["frequency"]
+	}
+	if data["interval"] != "" {
+		interval, err := strconv.Atoi(data["interval"])
+		if err != nil || interval < 1 || interval > 1000 {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid interval",
+			})
+		}
+		rule.Interval = interval
+	}
+	if data["start_date"] != "" {
+		parsedDate, err := time.Parse("2006-01-02", data["start_date"])
+		if err != nil {
+			return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		rule.StartDate = parsedDate
+	}
+	if value, ok := data["end_date"]; ok {
+		rule.EndDate = nil
+		if value != "" {
+			parsedDate, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid date format",
+				})
+			}
+			rule.EndDate = &parsedDate
+		}
+	}
+	if rule.EndDate != nil && rule.EndDate.Before(rule.StartDate) {
+		return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date range",
+		})
+	}
+	return true, nil
+}
+
+// materializeRecurring создаёт наступившие повторения сразу после изменения правила
+// и возвращает правило в актуальном состоянии. За запрос создаётся не больше одной
+// порции повторений; остальные, как и при ошибке, создаст планировщик
+func materializeRecurring(rule models.RecurringExpense) models.RecurringExpense {
+	if _, _, err := recurring.Materialize(rule.ID, time.Now()); err != nil {
+		logging.Logger.Error("Failed to create recurring expenses", zap.Uint("recurring_id", rule.ID), zap.Error(err))
+		return rule
+	}
+	if err := database.DB.First(&rule, rule.ID).Error; err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+	}
+	return rule
+}
+
+// usableCategory загружает категорию, которую можно назначить расходу; при ошибке ответ уже отправлен
+func usableCategory(c fiber.Ctx, categoryId string, householdId *uint) (models.Category, bool, error) {
+	var category models.Category
+	if err := database.DB.Where("id = ?", categoryId).
+		Scopes(households.UsableCategories(middleware.CurrentUserID(c), householdId)).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return category, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Category not found",
+			})
+		}
+		return category, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return category, true, nil
+}
+
+// findRecurring загружает правило из параметра id, которое видит текущий пользователь
+func findRecurring(c fiber.Ctx) (models.RecurringExpense, bool, error) {
+	var rule models.RecurringExpense
+	ruleId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return rule, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid recurring expense ID",
+		})
+	}
+	err = database.DB.Where("id = ?", ruleId).
+		Scopes(households.VisibleRecurringExpenses(middleware.CurrentUserID(c))).First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return rule, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Recurring expense not found",
+			})
+		}
+		return rule, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	return rule, true, nil
+}
+
+// findEditableRecurring загружает правило, которое текущий пользователь может изменять
+func findEditableRecurring(c fiber.Ctx) (models.RecurringExpense, bool, error) {
+	rule, ok, err := findRecurring(c)
+	if !ok {
+		return rule, false, err
+	}
+	if rule.HouseholdID != nil {
+		if _, ok, err := authorizeHousehold(c, *rule.HouseholdID,
+			models.HouseholdRoleOwner, models.HouseholdRoleEditor); !ok {
+			return rule, false, err
+		}
+	}
+	return rule, true, nil
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"project/database"
+	"project/expenses"
+	"project/households"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"project/reports"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetCategoryReport возвращает разбивку расходов за период по категориям
+func GetCategoryReport(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get category report")
+
+	scope, ok, err := expenseScope(c)
+	if !ok {
+		return err
+	}
+	period, ok, err := expensePeriod(c)
+	if !ok {
+		return err
+	}
+	target, ok, err := reportCurrency(c)
+	if !ok {
+		return err
+	}
+	var categories func(*gorm.DB) *gorm.DB
+	if value := c.Query("include_empty"); value != "" {
+		includeEmpty, err := strconv.ParseBool(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid include_empty value",
+			})
+		}
+		if includeEmpty {
+			categories = categoryScope(c)
+		}
+	}
+
+	breakdown, err := reports.ByCategory(database.DB.Model(&models.Expense{}).Scopes(scope, period.Scope),
+		categories, target)
+	if err != nil {
+		return conversionError(c, err, "Error building report")
+	}
+	return c.JSON(fiber.Map{
+		"currency":   target,
+		"period":     periodRange(period),
+		"total":      breakdown.Total,
+		"count":      breakdown.Count,
+		"categories": breakdown.Categories,
+	})
+}
+
+// weekdays — допустимые значения параметра week_start
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday, "thursday": time.Thursday,
+	"friday": time.Friday, "saturday": time.Saturday, "sunday": time.Sunday,
+}
+
+// GetTimeseriesReport возвращает суммы расходов по дням, неделям, месяцам или годам.
+// Без конца периода ряд строится по сегодняшний день в часовом поясе пользователя,
+// без начала — с самого раннего расхода
+func GetTimeseriesReport(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get timeseries report")
+
+	scope, ok, err := expenseScope(c)
+	if !ok {
+		return err
+	}
+	period, ok, err := expensePeriod(c)
+	if !ok {
+		return err
+	}
+	target, ok, err := reportCurrency(c)
+	if !ok {
+		return err
+	}
+	loc, ok, err := reportLocation(c)
+	if !ok {
+		return err
+	}
+
+	opts := reports.TimeseriesOptions{
+		Bucket:    reports.BucketMonth,
+		WeekStart: time.Monday,
+		From:      period.From,
+	}
+	if value := c.Query("bucket"); value != "" {
+		if !reports.IsValidBucket(value) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid bucket",
+			})
+		}
+		opts.Bucket = value
+	}
+	if value := c.Query("week_start"); value != "" {
+		weekStart, ok := weekdays[strings.ToLower(value)]
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid week_start",
+			})
+		}
+		opts.WeekStart = weekStart
+	}
+	if value := c.Query("by_category"); value != "" {
+		opts.ByCategory, err = strconv.ParseBool(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid by_category value",
+			})
+		}
+	}
+	if period.To == nil {
+		today := expenses.Today(time.Now(), loc)
+		period.To = &today
+	}
+	opts.To = *period.To
+	if period.From != nil && period.From.After(opts.To) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date range",
+		})
+	}
+
+	series, err := reports.Timeseries(database.DB.Model(&models.Expense{}).Scopes(scope, period.Scope), opts, target)
+	if err != nil {
+		if errors.Is(err, reports.ErrTooManyBuckets) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Too many buckets; use a shorter period or a larger bucket",
+			})
+		}
+		return conversionError(c, err, "Error building report")
+	}
+	if period.From == nil && len(series.Buckets) > 0 {
+		from, _ := time.Parse("2006-01-02", series.Buckets[0].Start)
+		period.From = &from
+	}
+	response := fiber.Map{
+		"currency":   target,
+		"period":     periodRange(period),
+		"bucket":     opts.Bucket,
+		"week_start": strings.ToLower(opts.WeekStart.String()),
+		"buckets":    series.Buckets,
+	}
+	if opts.ByCategory {
+		if series.Series == nil {
+			series.Series = []reports.Series{}
+		}
+		response["series"] = series.Series
+	}
+	return c.JSON(response)
+}
+
+// categoryScope выбирает категории, подходящие выборке расходов expenseScope;
+// членство в домохозяйстве к этому моменту уже проверено
+func categoryScope(c fiber.Ctx) func(*gorm.DB) *gorm.DB {
+	userId := middleware.CurrentUserID(c)
+	switch value := c.Query("household_id"); value {
+	case "":
+		return households.VisibleCategories(userId)
+	case "personal":
+		return households.UsableCategories(userId, nil)
+	default:
+		householdId, _ := strconv.ParseUint(value, 10, 0)
+		id := uint(householdId)
+		return households.UsableCategories(userId, &id)
+	}
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/auth"
+	"project/logging"
+	"project/middleware"
+	"strconv"
+)
+
+func RefreshToken(c fiber.Ctx) error {
+	logging.Logger.Info("Received a token refresh request")
+
+	var data map[string]string
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&data); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to parse request body",
+			})
+		}
+	}
+	refreshToken := data["refresh_token"]
+	if refreshToken == "" {
+		refreshToken = c.Cookies("refresh_token")
+	}
+	if refreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing refresh token",
+		})
+	}
+
+	pair, err := auth.RotateRefreshToken(refreshToken, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+			clearAuthCookies(c)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid refresh token",
+			})
+		}
+		if errors.Is(err, auth.ErrAccountDisabled) {
+			clearAuthCookies(c)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Account is disabled",
+			})
+		}
+		logging.Logger.Error("Error refreshing token:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	setAuthCookies(c, pair)
+	return c.JSON(tokenResponse("Token refreshed", pair))
+}
+
+func GetSessions(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get sessions")
+
+	userId := middleware.CurrentUserID(c)
+	currentSessionId := middleware.CurrentSessionID(c)
+	sessions, err := auth.ActiveSessions(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	result := make([]fiber.Map, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, fiber.Map{
+			"session_id":   session.ID,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == currentSessionId,
+		})
+	}
+	return c.JSON(result)
+}
+
+func DeleteSession(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete session")
+
+	userId := middleware.CurrentUserID(c)
+	sessionId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid session ID",
+		})
+	}
+	if err := auth.RevokeSession(userId, uint(sessionId)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Session not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete session",
+		})
+	}
+	if uint(sessionId) == middleware.CurrentSessionID(c) {
+		clearAuthCookies(c)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Session deleted successfully",
+	})
+}
+
+func DeleteOtherSessions(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete other sessions")
+
+	revoked, err := auth.RevokeOtherSessions(middleware.CurrentUserID(c), middleware.CurrentSessionID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete sessions",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Sessions deleted successfully",
+		"revoked": revoked,
+	})
+}
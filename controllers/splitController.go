@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"project/households"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+	"project/money"
+	"project/splits"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func GetExpenseSplit(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get expense split")
+
+	expenseId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid expense ID",
+		})
+	}
+	expense, ok, err := findVisibleExpense(c, uint(expenseId))
+	if !ok {
+		return err
+	}
+
+	shares, err := splits.ForExpense(expense.ID)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving split",
+		})
+	}
+	return c.JSON(splitResponse(expense, shares))
+}
+
+// SetExpenseSplit разделяет расход домохозяйства между участниками. Для способа
+// equal без списка участников расход делится на всех участников домохозяйства
+func SetExpenseSplit(c fiber.Ctx) error {
+	logging.Logger.Info("Request to split expense")
+
+	expenseId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid expense ID",
+		})
+	}
+	expense, ok, err := findEditableExpense(c, uint(expenseId))
+	if !ok {
+		return err
+	}
+
+	var data struct {
+		Method string `json:"method"`
+		Shares []struct {
+			UserID  uint         `json:"user_id"`
+			Amount  money.Amount `json:"amount"`
+			Percent float64      `json:"percent"`
+		} `json:"shares"`
+	}
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if expense.HouseholdID == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Only household expenses can be split",
+		})
+	}
+
+	shares := make([]splits.Share, 0, len(data.Shares))
+	for _, share := range data.Shares {
+		shares = append(shares, splits.Share{UserID: share.UserID, Amount: share.Amount, Percent: share.Percent})
+	}
+	if data.Method == models.SplitEqual && len(shares) == 0 {
+		members, err := households.Members(*expense.HouseholdID)
+		if err != nil {
+			logging.Logger.Error("Database error:", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		for _, member := range members {
+			shares = append(shares, splits.Share{UserID: member.UserID})
+		}
+	}
+
+	computed, err := splits.Apply(&expense, data.Method, shares)
+	if err != nil {
+		switch {
+		case errors.Is(err, splits.ErrExactSplit):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Exact shares must add up to the expense amount",
+			})
+		case errors.Is(err, splits.ErrInvalidSplit):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid split",
+				"methods": []string{models.SplitEqual, models.SplitExact, models.SplitPercent},
+			})
+		case errors.Is(err, splits.ErrNotMember):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "All participants must be household members",
+			})
+		}
+		logging.Logger.Error("Failed to split expense:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to split expense",
+		})
+	}
+
+	logging.Logger.Info("Expense split",
+		zap.Uint("expense_id", expense.ID),
+		zap.String("method", data.Method),
+		zap.Int("participants", len(computed)),
+	)
+	return c.JSON(splitResponse(expense, computed))
+}
+
+func DeleteExpenseSplit(c fiber.Ctx) error {
+	logging.Logger.Info("Request to remove expense split")
+
+	expenseId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid expense ID",
+		})
+	}
+	expense, ok, err := findEditableExpense(c, uint(expenseId))
+	if !ok {
+		return err
+	}
+
+	if err := splits.Clear(&expense); err != nil {
+		logging.Logger.Error("Failed to remove expense split:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove split",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Split removed successfully",
+	})
+}
+
+func GetHouseholdBalances(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get household balances")
+
+	householdId, _, ok, err := householdFromParam(c)
+	if !ok {
+		return err
+	}
+
+	debts, householdCurrency, ok, err := householdBalances(c, householdId)
+	if !ok {
+		return err
+	}
+
+	net := splits.NetBalances(debts)
+	userIds := make([]uint, 0, len(net))
+	for userId := range net {
+		userIds = append(userIds, userId)
+	}
+	sort.Slice(userIds, func(i, j int) bool { return userIds[i] < userIds[j] })
+	members := make([]fiber.Map, 0, len(userIds))
+	for _, userId := range userIds {
+		members = append(members, fiber.Map{
+			"user_id": userId,
+			"balance": net[userId],
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"currency": householdCurrency,
+		"debts":    debts,
+		"members":  members,
+	})
+}
+
+func GetSimplifiedDebts(c fiber.Ctx) error {
+	logging.Logger.Info("Request to simplify household debts")
+
+	householdId, _, ok, err := householdFromParam(c)
+	if !ok {
+		return err
+	}
+
+	debts, householdCurrency, ok, err := householdBalances(c, householdId)
+	if !ok {
+		return err
+	}
+	transfers := splits.Simplify(debts)
+	if transfers == nil {
+		transfers = []splits.Debt{}
+	}
+	return c.JSON(fiber.Map{
+		"currency":  householdCurrency,
+		"transfers": transfers,
+	})
+}
+
+func GetSettlements(c fiber.Ctx) error {
+	logging.Logger.Info("Request to get settlements")
+
+	householdId, _, ok, err := householdFromParam(c)
+	if !ok {
+		return err
+	}
+
+	settlements, err := splits.Settlements(householdId)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving settlements",
+		})
+	}
+	return c.JSON(settlements)
+}
+
+// CreateSettlement регистрирует погашение долга; по умолчанию платит текущий пользователь
+func CreateSettlement(c fiber.Ctx) error {
+	logging.Logger.Info("Request to record settlement")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return err
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["to_user_id"] == "" || data["amount"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	settlement := models.Settlement{
+		HouseholdID: householdId,
+		FromUserID:  middleware.CurrentUserID(c),
+		Note:        data["note"],
+		CreatedBy:   middleware.CurrentUserID(c),
+	}
+	if data["from_user_id"] != "" {
+		fromUserId, err := strconv.ParseUint(data["from_user_id"], 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid user ID",
+			})
+		}
+		settlement.FromUserID = uint(fromUserId)
+	}
+	toUserId, err := strconv.ParseUint(data["to_user_id"], 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	settlement.ToUserID = uint(toUserId)
+	settlement.Currency, err = households.Currency(householdId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	amount, ok, err := parseAmount(c, data["amount"], settlement.Currency)
+	if !ok {
+		return err
+	}
+	settlement.Amount = amount
+	if data["date"] != "" {
+		parsedDate, err := time.Parse("2006-01-02", data["date"])
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		settlement.Date = parsedDate
+	}
+
+	if err := splits.RecordSettlement(&settlement); err != nil {
+		switch {
+		case errors.Is(err, splits.ErrInvalidSettlement):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Settlement must be a positive amount between two different members",
+			})
+		case errors.Is(err, splits.ErrNotMember):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Both users must be household members",
+			})
+		}
+		logging.Logger.Error("Failed to record settlement:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record settlement",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(settlement)
+}
+
+func DeleteSettlement(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete settlement")
+
+	householdId, _, ok, err := householdFromParam(c, models.HouseholdRoleOwner, models.HouseholdRoleEditor)
+	if !ok {
+		return err
+	}
+	settlementId, err := strconv.ParseUint(c.Params("settlement_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid settlement ID",
+		})
+	}
+
+	deleted, err := splits.DeleteSettlement(householdId, uint(settlementId))
+	if err != nil {
+		logging.Logger.Error("Failed to delete settlement:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete settlement",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Settlement not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Settlement deleted successfully",
+	})
+}
+
+// householdBalances считает долги участников в валюте домохозяйства; при ошибке ответ уже отправлен
+func householdBalances(c fiber.Ctx, householdId uint) ([]splits.Debt, string, bool, error) {
+	householdCurrency, err := households.Currency(householdId)
+	if err != nil {
+		logging.Logger.Error("Database error:", zap.Error(err))
+		return nil, "", false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving balances",
+		})
+	}
+	debts, err := splits.Balances(householdId, householdCurrency)
+	if err != nil {
+		return nil, "", false, conversionError(c, err, "Error retrieving balances")
+	}
+	return debts, householdCurrency, true, nil
+}
+
+func splitResponse(expense models.Expense, shares []models.ExpenseShare) fiber.Map {
+	return fiber.Map{
+		"expense_id": expense.ID,
+		"paid_by":    expense.UserID,
+		"amount":     expense.Amount,
+		"currency":   expense.Currency,
+		"method":     expense.SplitMethod,
+		"shares":     shares,
+	}
+}
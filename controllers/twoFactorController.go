@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"project/auth"
+	"project/logging"
+	"project/middleware"
+	"project/models"
+)
+
+func SetupTwoFactor(c fiber.Ctx) error {
+	logging.Logger.Info("Request to set up two-factor authentication")
+
+	secret, uri, err := auth.BeginTOTPEnrollment(middleware.CurrentUser(c))
+	if err != nil {
+		if errors.Is(err, auth.ErrTwoFactorAlreadyEnabled) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Two-factor authentication is already enabled",
+			})
+		}
+		logging.Logger.Error("Failed to start two-factor enrollment:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+func ConfirmTwoFactor(c fiber.Ctx) error {
+	logging.Logger.Info("Request to confirm two-factor authentication")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["code"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	codes, err := auth.ConfirmTOTPEnrollment(middleware.CurrentUser(c), data["code"])
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrTwoFactorAlreadyEnabled):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Two-factor authentication is already enabled",
+			})
+		case errors.Is(err, auth.ErrTwoFactorNotEnrolled):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Two-factor setup was not started",
+			})
+		case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid code",
+			})
+		}
+		logging.Logger.Error("Failed to confirm two-factor enrollment:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+func DisableTwoFactor(c fiber.Ctx) error {
+	logging.Logger.Info("Request to disable two-factor authentication")
+
+	user := middleware.CurrentUser(c)
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two-factor authentication is not enabled",
+		})
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["password"] == "" || data["code"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(data["password"])); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+	if ok, err := verifySecondFactor(c, user, data["code"]); !ok {
+		return err
+	}
+
+	if err := auth.DisableTOTP(user.ID); err != nil {
+		logging.Logger.Error("Failed to disable two-factor authentication:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func RegenerateRecoveryCodes(c fiber.Ctx) error {
+	logging.Logger.Info("Request to regenerate recovery codes")
+
+	user := middleware.CurrentUser(c)
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two-factor authentication is not enabled",
+		})
+	}
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["code"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	if ok, err := verifySecondFactor(c, user, data["code"]); !ok {
+		return err
+	}
+
+	codes, err := auth.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		logging.Logger.Error("Failed to regenerate recovery codes:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+func LoginTwoFactor(c fiber.Ctx) error {
+	logging.Logger.Info("Received a two-factor login request")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["challenge_token"] == "" || data["code"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	user, err := auth.ConsumeTwoFactorChallenge(data["challenge_token"])
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidChallenge) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid or expired challenge",
+			})
+		}
+		logging.Logger.Error("Failed to parse challenge:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	if blocked, err := checkLoginThrottle(c, user.Email); blocked {
+		return err
+	}
+	if err := auth.VerifySecondFactor(*user, data["code"]); err != nil {
+		if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+			return loginFailed(c, user.Email)
+		}
+		logging.Logger.Error("Failed to verify second factor:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+	if err := auth.ResetLoginFailures(user.Email, c.IP()); err != nil {
+		logging.Logger.Error("Failed to reset login failures:", zap.Error(err))
+	}
+
+	return completeLogin(c, *user)
+}
+
+// verifySecondFactor проверяет код TOTP или код восстановления; при неудаче
+// ответ клиенту уже отправлен и возвращается false
+func verifySecondFactor(c fiber.Ctx, user models.User, code string) (bool, error) {
+	err := auth.VerifySecondFactor(user, code)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+		return false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid code",
+		})
+	}
+	logging.Logger.Error("Failed to verify second factor:", zap.Error(err))
+	return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Internal server error",
+	})
+}
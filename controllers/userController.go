@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"project/account"
+	"project/currency"
+	"project/logging"
+	"project/middleware"
+	"time"
+)
+
+func UpdateUser(c fiber.Ctx) error {
+	logging.Logger.Info("Request to update user")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["username"] == "" && data["email"] == "" && data["base_currency"] == "" && data["timezone"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+	if data["email"] != "" && !isValidEmail(data["email"]) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email address",
+		})
+	}
+
+	baseCurrency := ""
+	if data["base_currency"] != "" {
+		code, err := currency.Normalize(data["base_currency"])
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unsupported currency",
+			})
+		}
+		baseCurrency = code
+	}
+	if data["timezone"] != "" {
+		if _, err := time.LoadLocation(data["timezone"]); err != nil || data["timezone"] == "Local" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown timezone",
+			})
+		}
+	}
+
+	user := middleware.CurrentUser(c)
+	emailChanged, err := account.UpdateProfile(&user, data["username"], data["email"], baseCurrency, data["timezone"])
+	if err != nil {
+		if errors.Is(err, account.ErrUsernameTaken) || errors.Is(err, account.ErrEmailTaken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Email or username already exists",
+			})
+		}
+		logging.Logger.Error("Failed to update user:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update user",
+		})
+	}
+
+	if emailChanged {
+		if err := sendVerificationEmail(user); err != nil {
+			logging.Logger.Error("Failed to send verification email:", zap.Error(err))
+		}
+	}
+
+	return c.JSON(user)
+}
+
+func ChangePassword(c fiber.Ctx) error {
+	logging.Logger.Info("Request to change password")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["current_password"] == "" || data["new_password"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	err := account.ChangePassword(middleware.CurrentUser(c), data["current_password"], data["new_password"],
+		middleware.CurrentSessionID(c))
+	if err != nil {
+		if errors.Is(err, account.ErrInvalidPassword) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid credentials",
+			})
+		}
+		logging.Logger.Error("Failed to change password:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password changed successfully",
+	})
+}
+
+func DeleteUser(c fiber.Ctx) error {
+	logging.Logger.Info("Request to delete user")
+
+	var data map[string]string
+	if err := c.Bind().Body(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if data["password"] == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required fields",
+		})
+	}
+
+	deletionAt, err := account.ScheduleDeletion(middleware.CurrentUser(c), data["password"])
+	if err != nil {
+		if errors.Is(err, account.ErrInvalidPassword) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid credentials",
+			})
+		}
+		logging.Logger.Error("Failed to schedule account deletion:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete user",
+		})
+	}
+
+	clearAuthCookies(c)
+
+	return c.JSON(fiber.Map{
+		"message":               "Account scheduled for deletion",
+		"deletion_scheduled_at": deletionAt,
+	})
+}
+
+func RestoreUser(c fiber.Ctx) error {
+	logging.Logger.Info("Request to restore user")
+
+	if err := account.CancelDeletion(middleware.CurrentUserID(c)); err != nil {
+		if errors.Is(err, account.ErrDeletionNotPending) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Account deletion is not scheduled",
+			})
+		}
+		logging.Logger.Error("Failed to cancel account deletion:", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to restore user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account deletion cancelled",
+	})
+}
@@ -0,0 +1,71 @@
+package currency
+
+import (
+	"errors"
+	"strings"
+
+	"project/money"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// minorUnits — число знаков после запятой для валют ISO 4217. Суммы хранятся
+// в сотых долях, поэтому валюты с тремя знаками (KWD, BHD) не поддерживаются
+var minorUnits = map[string]int{
+	"AED": 2, "AMD": 2, "AUD": 2, "AZN": 2, "BGN": 2, "BRL": 2, "BYN": 2,
+	"CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2,
+	"GBP": 2, "GEL": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2,
+	"ISK": 0, "JPY": 0, "KGS": 2, "KRW": 0, "KZT": 2, "MDL": 2, "MXN": 2,
+	"NOK": 2, "NZD": 2, "PLN": 2, "RON": 2, "RSD": 2, "RUB": 2, "SEK": 2,
+	"SGD": 2, "THB": 2, "TJS": 2, "TRY": 2, "UAH": 2, "USD": 2, "UZS": 2,
+	"VND": 0, "ZAR": 2,
+}
+
+// Normalize приводит код валюты к верхнему регистру и проверяет, что он поддерживается
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := minorUnits[code]; !ok {
+		return "", ErrUnsupportedCurrency
+	}
+	return code, nil
+}
+
+// IsSupported сообщает, поддерживается ли валюта
+func IsSupported(code string) bool {
+	_, ok := minorUnits[code]
+	return ok
+}
+
+// ParseAmount строго разбирает положительную сумму в валюте: для валют без
+// разменной единицы дробная часть не допускается
+func ParseAmount(s, code string) (money.Amount, error) {
+	amount, err := money.ParsePositive(s)
+	if err != nil {
+		return 0, err
+	}
+	if amount.Round(decimals(code)) != amount {
+		return 0, money.ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+// Step возвращает разменную единицу валюты в сотых долях: 1 для копеек, 100 для иены
+func Step(code string) int64 {
+	step := int64(1)
+	for i := decimals(code); i < money.Scale; i++ {
+		step *= 10
+	}
+	return step
+}
+
+// Round округляет сумму до разменной единицы валюты
+func Round(amount money.Amount, code string) money.Amount {
+	return amount.Round(decimals(code))
+}
+
+func decimals(code string) int {
+	if units, ok := minorUnits[code]; ok {
+		return units
+	}
+	return money.Scale
+}
@@ -0,0 +1,155 @@
+package currency
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"project/config"
+	"project/logging"
+	"project/models"
+	"project/money"
+)
+
+// RateProvider — источник курсов валют. Rates возвращает курсы, известные
+// источнику на дату; источник может вернуть и курсы за другие даты
+type RateProvider interface {
+	Name() string
+	Rates(date time.Time) ([]models.ExchangeRate, error)
+}
+
+var ErrNoProvider = errors.New("exchange rate provider is not configured")
+
+// Provider — источник курсов, выбранный в конфигурации; nil, если курсы
+// загружаются только вручную
+var Provider RateProvider
+
+// Setup проверяет настройки валют и создаёт источник курсов
+func Setup() error {
+	cfg := config.GetConfig().Currency
+	if !IsSupported(cfg.Default) {
+		return fmt.Errorf("unsupported default currency %q", cfg.Default)
+	}
+	switch cfg.Provider {
+	case "none":
+		Provider = nil
+	case "csv":
+		Provider = &CSVProvider{Path: cfg.RatesFile}
+	default:
+		return fmt.Errorf("unknown exchange rate provider %q", cfg.Provider)
+	}
+	return nil
+}
+
+// StartRateSync периодически загружает курсы из источника
+func StartRateSync() {
+	if Provider == nil {
+		return
+	}
+	go func() {
+		syncRates()
+		ticker := time.NewTicker(config.GetConfig().Currency.SyncInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			syncRates()
+		}
+	}()
+}
+
+func syncRates() {
+	imported, err := Sync()
+	if err != nil {
+		logging.Logger.Error("Failed to sync exchange rates", zap.Error(err))
+		return
+	}
+	logging.Logger.Info("Exchange rates synced", zap.String("provider", Provider.Name()), zap.Int("rates", imported))
+}
+
+// Sync загружает курсы из источника и возвращает число сохранённых курсов
+func Sync() (int, error) {
+	if Provider == nil {
+		return 0, ErrNoProvider
+	}
+	rates, err := Provider.Rates(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if err := Import(rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// CSVProvider читает курсы из локального файла и работает без доступа к сети.
+// Формат файла описан в ParseCSV
+type CSVProvider struct {
+	Path string
+}
+
+func (p *CSVProvider) Name() string {
+	return "csv"
+}
+
+func (p *CSVProvider) Rates(time.Time) ([]models.ExchangeRate, error) {
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseCSV(file, p.Name())
+}
+
+// ParseCSV разбирает курсы в формате "дата,базовая валюта,котируемая валюта,курс",
+// например "2024-03-01,USD,RUB,90.8"; строка заголовка допускается
+func ParseCSV(r io.Reader, source string) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	var rates []models.ExchangeRate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+
+		date, err := time.Parse("2006-01-02", record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+		base, err := Normalize(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: unsupported currency %q", line, record[1])
+		}
+		quote, err := Normalize(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: unsupported currency %q", line, record[2])
+		}
+		if base == quote {
+			return nil, fmt.Errorf("line %d: base and quote currencies must differ", line)
+		}
+		rate, err := money.ParseRate(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+		}
+		rates = append(rates, models.ExchangeRate{
+			Date:   date,
+			Base:   base,
+			Quote:  quote,
+			Rate:   rate,
+			Source: source,
+		})
+	}
+	return rates, nil
+}
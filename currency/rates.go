@@ -0,0 +1,195 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/database"
+	"project/models"
+	"project/money"
+)
+
+// MissingRateError сообщает, что для пересчёта не нашлось курса на дату или раньше
+type MissingRateError struct {
+	From string
+	To   string
+	Date time.Time
+}
+
+func (e *MissingRateError) Error() string {
+	return fmt.Sprintf("no exchange rate %s/%s on or before %s", e.From, e.To, e.Date.Format("2006-01-02"))
+}
+
+// Import сохраняет курсы; курс той же пары на ту же дату перезаписывается
+func Import(rates []models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	// Одна пара на одну дату может встретиться в пакете лишь раз, иначе
+	// ON CONFLICT не сможет обновить строку; остаётся последнее значение
+	latest := make(map[string]int, len(rates))
+	unique := make([]models.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		rate.Date = day(rate.Date)
+		key := rate.Date.Format("2006-01-02") + rate.Base + rate.Quote
+		if i, ok := latest[key]; ok {
+			unique[i] = rate
+			continue
+		}
+		latest[key] = len(unique)
+		unique = append(unique, rate)
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}, {Name: "base"}, {Name: "quote"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(&unique, 500).Error
+}
+
+// List возвращает курсы за период; пустые base и quote не ограничивают выборку
+func List(base, quote string, from, to time.Time) ([]models.ExchangeRate, error) {
+	query := database.DB.Where("date BETWEEN ? AND ?", day(from), day(to))
+	if base != "" {
+		query = query.Where("base = ?", base)
+	}
+	if quote != "" {
+		query = query.Where("quote = ?", quote)
+	}
+	var rates []models.ExchangeRate
+	err := query.Order("date DESC, base, quote").Find(&rates).Error
+	return rates, err
+}
+
+// Delete удаляет курс пары на дату; возвращает false, если его не было
+func Delete(base, quote string, date time.Time) (bool, error) {
+	result := database.DB.Where("base = ? AND quote = ? AND date = ?", base, quote, day(date)).
+		Delete(&models.ExchangeRate{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Converter пересчитывает суммы в одну валюту по курсу на дату расхода.
+// Используется последний курс не позже этой даты: прямой, обратный или кросс-курс
+// через общую третью валюту. Найденные курсы кешируются на время жизни конвертера
+type Converter struct {
+	Target string
+	db     *gorm.DB
+	cache  map[rateKey]money.Rate
+}
+
+type rateKey struct {
+	from string
+	date time.Time
+}
+
+// NewConverter создаёт конвертер в валюту target
+func NewConverter(target string) *Converter {
+	return &Converter{Target: target, db: database.DB, cache: make(map[rateKey]money.Rate)}
+}
+
+// Convert пересчитывает сумму из валюты from в целевую валюту
+func (c *Converter) Convert(amount money.Amount, from string, date time.Time) (money.Amount, error) {
+	if from == c.Target {
+		return amount, nil
+	}
+	rate, err := c.Rate(from, date)
+	if err != nil {
+		return 0, err
+	}
+	return amount.Convert(rate), nil
+}
+
+// Rate возвращает курс валюты from к целевой валюте на дату
+func (c *Converter) Rate(from string, date time.Time) (money.Rate, error) {
+	if from == c.Target {
+		return money.OneRate(), nil
+	}
+	key := rateKey{from: from, date: day(date)}
+	if rate, ok := c.cache[key]; ok {
+		return rate, nil
+	}
+	rate, err := c.lookup(from, key.date)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	c.cache[key] = rate
+	return rate, nil
+}
+
+func (c *Converter) lookup(from string, date time.Time) (money.Rate, error) {
+	// Последний известный курс каждой пары, в которой участвует одна из двух валют
+	var rates []models.ExchangeRate
+	err := c.db.Raw(`SELECT DISTINCT ON (base, quote) * FROM exchange_rates
+		WHERE (base IN ? OR quote IN ?) AND date <= ?
+		ORDER BY base, quote, date DESC`,
+		[]string{from, c.Target}, []string{from, c.Target}, date).
+		Scan(&rates).Error
+	if err != nil {
+		return money.Rate{}, err
+	}
+
+	// toFrom[x] — курс: за единицу валюты from дают toFrom[x] единиц x; toTarget аналогично
+	toFrom := make(map[string]money.Rate)
+	toTarget := make(map[string]money.Rate)
+	for _, rate := range rates {
+		switch {
+		case rate.Base == from:
+			toFrom[rate.Quote] = rate.Rate
+		case rate.Quote == from:
+			toFrom[rate.Base] = rate.Rate.Inverse()
+		}
+		switch {
+		case rate.Base == c.Target:
+			toTarget[rate.Quote] = rate.Rate
+		case rate.Quote == c.Target:
+			toTarget[rate.Base] = rate.Rate.Inverse()
+		}
+	}
+
+	if rate, ok := toFrom[c.Target]; ok {
+		return rate, nil
+	}
+	intermediates := make([]string, 0, len(toFrom))
+	for intermediate := range toFrom {
+		intermediates = append(intermediates, intermediate)
+	}
+	sort.Strings(intermediates)
+	for _, intermediate := range intermediates {
+		if targetRate, ok := toTarget[intermediate]; ok {
+			return toFrom[intermediate].Mul(targetRate.Inverse()), nil
+		}
+	}
+	return money.Rate{}, &MissingRateError{From: from, To: c.Target, Date: date}
+}
+
+// Total суммирует расходы запроса в целевой валюте. Запрос должен быть построен
+// по модели Expense; суммы группируются по валюте и дню, чтобы пересчитать каждую
+// группу по курсу своей даты
+func (c *Converter) Total(query *gorm.DB) (money.Amount, error) {
+	var groups []struct {
+		Currency string
+		Day      time.Time
+		Amount   money.Amount
+	}
+	err := query.Select("expenses.currency AS currency, DATE(expenses.date) AS day, SUM(expenses.amount) AS amount").
+		Group("expenses.currency, DATE(expenses.date)").
+		Scan(&groups).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var total money.Amount
+	for _, group := range groups {
+		converted, err := c.Convert(group.Amount, group.Currency, group.Day)
+		if err != nil {
+			return 0, err
+		}
+		total += converted
+	}
+	return Round(total, c.Target), nil
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -25,6 +25,35 @@ func ConnectDB() (*gorm.DB, error) {
 
 	DB = db
 
-	db.AutoMigrate(&models.User{}, &models.Category{}, &models.Expense{})
+	if err := migrateMoneyColumns(db); err != nil {
+		return nil, err
+	}
+	db.AutoMigrate(
+		&models.User{},
+		&models.Category{},
+		&models.Expense{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.APIToken{},
+		&models.LoginThrottle{},
+		&models.UserIdentity{},
+		&models.OIDCState{},
+		&models.Household{},
+		&models.HouseholdMember{},
+		&models.HouseholdInvitation{},
+		&models.ExpenseShare{},
+		&models.Settlement{},
+		&models.ExchangeRate{},
+		&models.Budget{},
+		&models.BudgetAlert{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.NotificationWebhook{},
+		&models.RecurringExpense{},
+		&models.RecurringException{},
+	)
 	return db, nil
 }
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/models"
+)
+
+// migrateMoneyColumns переводит суммы, хранившиеся в double precision, в целые
+// сотые доли. Выполняется до AutoMigrate: тот сменил бы тип колонки простым
+// приведением и отбросил копейки
+func migrateMoneyColumns(db *gorm.DB) error {
+	for _, model := range []interface{}{&models.Expense{}, &models.ExpenseShare{}, &models.Settlement{}} {
+		if !db.Migrator().HasTable(model) {
+			continue
+		}
+		columnTypes, err := db.Migrator().ColumnTypes(model)
+		if err != nil {
+			return err
+		}
+		for _, column := range columnTypes {
+			if column.Name() != "amount" {
+				continue
+			}
+			switch strings.ToLower(column.DatabaseTypeName()) {
+			case "float4", "float8", "real", "double precision", "numeric":
+			default:
+				continue
+			}
+
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+			err := db.Exec("ALTER TABLE ? ALTER COLUMN amount TYPE bigint USING ROUND(amount * 100)::bigint",
+				clause.Table{Name: stmt.Schema.Table}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
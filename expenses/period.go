@@ -0,0 +1,43 @@
+package expenses
+
+import (
+	"errors"
+	"time"
+)
+
+// Именованные периоды отчётов
+const (
+	PeriodThisMonth  = "this_month"
+	PeriodLastMonth  = "last_month"
+	PeriodThisYear   = "this_year"
+	PeriodLast30Days = "last_30_days"
+)
+
+var ErrUnknownPeriod = errors.New("unknown period")
+
+// ResolvePeriod возвращает первый и последний день именованного периода.
+// Сегодняшняя дата берётся в часовом поясе пользователя, а границы, как и даты
+// расходов, — календарные дни в UTC
+func ResolvePeriod(name string, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	today := Today(now, loc)
+	monthStart := today.AddDate(0, 0, 1-today.Day())
+
+	switch name {
+	case PeriodThisMonth:
+		return monthStart, monthStart.AddDate(0, 1, -1), nil
+	case PeriodLastMonth:
+		return monthStart.AddDate(0, -1, 0), monthStart.AddDate(0, 0, -1), nil
+	case PeriodThisYear:
+		yearStart := time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return yearStart, yearStart.AddDate(1, 0, -1), nil
+	case PeriodLast30Days:
+		return today.AddDate(0, 0, -29), today, nil
+	}
+	return time.Time{}, time.Time{}, ErrUnknownPeriod
+}
+
+// Today возвращает сегодняшний день в часовом поясе loc как календарную дату в UTC
+func Today(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,196 @@
+package expenses
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"project/models"
+	"project/money"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// sortColumns — поля, по которым можно сортировать расходы
+var sortColumns = map[string]string{
+	"date":   "expenses.date",
+	"amount": "expenses.amount",
+	"name":   "expenses.name",
+	"id":     "expenses.id",
+}
+
+// Filter — условия выборки расходов; пустые поля выборку не ограничивают
+type Filter struct {
+	// From и To — границы периода включительно; To действует до конца дня
+	From        *time.Time
+	To          *time.Time
+	CategoryIDs []uint
+	// Суммы сравниваются в валюте расхода, без пересчёта
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+	// Name — подстрока названия без учёта регистра
+	Name string
+}
+
+// Scope применяет фильтр к запросу по модели Expense
+func (f Filter) Scope(db *gorm.DB) *gorm.DB {
+	if f.From != nil {
+		db = db.Where("expenses.date >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("expenses.date < ?", f.To.AddDate(0, 0, 1))
+	}
+	if len(f.CategoryIDs) > 0 {
+		db = db.Where("expenses.category_id IN ?", f.CategoryIDs)
+	}
+	if f.MinAmount != nil {
+		db = db.Where("expenses.amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		db = db.Where("expenses.amount <= ?", *f.MaxAmount)
+	}
+	if f.Name != "" {
+		db = db.Where(`expenses.name ILIKE ? ESCAPE '\'`, "%"+EscapeLike(f.Name)+"%")
+	}
+	return db
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// EscapeLike экранирует % и _, чтобы строка поиска совпадала в LIKE буквально
+// (с ESCAPE '\')
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// Sort — порядок выдачи; при равных значениях поля расходы упорядочены по ID
+// в том же направлении, поэтому порядок всегда однозначен
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort разбирает поле и направление сортировки; по умолчанию — новые расходы первыми
+func ParseSort(field, order string) (Sort, error) {
+	sort := Sort{Field: "date", Desc: true}
+	if field != "" {
+		if _, ok := sortColumns[field]; !ok {
+			return sort, ErrInvalidSort
+		}
+		sort.Field = field
+	}
+	switch strings.ToLower(order) {
+	case "":
+	case "asc":
+		sort.Desc = false
+	case "desc":
+		sort.Desc = true
+	default:
+		return sort, ErrInvalidSort
+	}
+	return sort, nil
+}
+
+// cursor — позиция последнего выданного расхода. Сортировка сохраняется в курсоре,
+// чтобы курсор нельзя было применить к выдаче с другим порядком
+type cursor struct {
+	Field string `json:"f"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+// List возвращает страницу расходов запроса после позиции after (пусто — первая
+// страница) и курсор следующей страницы; пустой курсор означает, что страница последняя
+func List(query *gorm.DB, sort Sort, after string, limit int) ([]models.Expense, string, error) {
+	column := sortColumns[sort.Field]
+	direction, compare := "ASC", ">"
+	if sort.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	if after != "" {
+		position, value, err := decodeCursor(after, sort)
+		if err != nil {
+			return nil, "", err
+		}
+		if sort.Field == "id" {
+			query = query.Where("expenses.id "+compare+" ?", position.ID)
+		} else {
+			query = query.Where("("+column+", expenses.id) "+compare+" (?, ?)", value, position.ID)
+		}
+	}
+	if sort.Field != "id" {
+		query = query.Order(column + " " + direction)
+	}
+
+	var expenses []models.Expense
+	err := query.Order("expenses.id " + direction).Limit(limit + 1).Find(&expenses).Error
+	if err != nil {
+		return nil, "", err
+	}
+	if len(expenses) <= limit {
+		return expenses, "", nil
+	}
+	expenses = expenses[:limit]
+	return expenses, encodeCursor(sort, expenses[limit-1]), nil
+}
+
+func encodeCursor(sort Sort, last models.Expense) string {
+	position := cursor{Field: sort.Field, Desc: sort.Desc, ID: last.ID}
+	switch sort.Field {
+	case "date":
+		position.Value = last.Date.Format(time.RFC3339Nano)
+	case "amount":
+		position.Value = strconv.FormatInt(last.Amount.Minor(), 10)
+	case "name":
+		position.Value = last.Name
+	}
+	data, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор и возвращает значение поля сортировки в типе колонки
+func decodeCursor(s string, sort Sort) (cursor, interface{}, error) {
+	var position cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return position, nil, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &position); err != nil {
+		return position, nil, ErrInvalidCursor
+	}
+	if position.Field != sort.Field || position.Desc != sort.Desc {
+		return position, nil, ErrInvalidCursor
+	}
+
+	switch sort.Field {
+	case "date":
+		date, err := time.Parse(time.RFC3339Nano, position.Value)
+		if err != nil {
+			return position, nil, ErrInvalidCursor
+		}
+		return position, date, nil
+	case "amount":
+		minor, err := strconv.ParseInt(position.Value, 10, 64)
+		if err != nil {
+			return position, nil, ErrInvalidCursor
+		}
+		return position, money.FromMinor(minor), nil
+	case "name":
+		return position, position.Value, nil
+	}
+	return position, nil, nil
+}
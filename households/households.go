@@ -0,0 +1,347 @@
+package households
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"project/auth"
+	"project/config"
+	"project/database"
+	"project/models"
+	"project/splits"
+)
+
+var (
+	ErrNotMember         = errors.New("not a household member")
+	ErrInsufficientRole  = errors.New("insufficient household role")
+	ErrInvalidRole       = errors.New("invalid household role")
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+	ErrAlreadyMember     = errors.New("already a household member")
+	ErrLastOwner         = errors.New("household must keep at least one owner")
+	ErrHouseholdNotEmpty = errors.New("household still has expenses")
+)
+
+// IsValidRole проверяет, что роль участника домохозяйства известна
+func IsValidRole(role string) bool {
+	switch role {
+	case models.HouseholdRoleOwner, models.HouseholdRoleEditor, models.HouseholdRoleViewer:
+		return true
+	}
+	return false
+}
+
+// CanEdit сообщает, может ли участник с этой ролью изменять расходы и категории домохозяйства
+func CanEdit(role string) bool {
+	return role == models.HouseholdRoleOwner || role == models.HouseholdRoleEditor
+}
+
+// Authorize возвращает роль пользователя в домохозяйстве. Если роли перечислены,
+// пользователь должен обладать одной из них
+func Authorize(householdId, userId uint, roles ...string) (string, error) {
+	var member models.HouseholdMember
+	err := database.DB.Where("household_id = ? AND user_id = ?", householdId, userId).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	if len(roles) == 0 {
+		return member.Role, nil
+	}
+	for _, role := range roles {
+		if member.Role == role {
+			return member.Role, nil
+		}
+	}
+	return member.Role, ErrInsufficientRole
+}
+
+// Create создаёт домохозяйство, владельцем которого становится пользователь
+func Create(userId uint, name, currency string) (*models.Household, error) {
+	household := models.Household{Name: name, Currency: currency}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&household).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.HouseholdMember{
+			HouseholdID: household.ID,
+			UserID:      userId,
+			Role:        models.HouseholdRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &household, nil
+}
+
+// Currency возвращает валюту, в которой ведутся расчёты домохозяйства
+func Currency(householdId uint) (string, error) {
+	var household models.Household
+	err := database.DB.Select("currency").First(&household, householdId).Error
+	return household.Currency, err
+}
+
+// ListForUser возвращает членства пользователя вместе с домохозяйствами
+func ListForUser(userId uint) ([]models.HouseholdMember, error) {
+	var memberships []models.HouseholdMember
+	err := database.DB.Preload("Household").Where("user_id = ?", userId).
+		Order("created_at").Find(&memberships).Error
+	return memberships, err
+}
+
+// Members возвращает участников домохозяйства вместе с их аккаунтами
+func Members(householdId uint) ([]models.HouseholdMember, error) {
+	var members []models.HouseholdMember
+	err := database.DB.Preload("User").Where("household_id = ?", householdId).
+		Order("created_at").Find(&members).Error
+	return members, err
+}
+
+// Delete удаляет пустое домохозяйство вместе с его категориями и приглашениями
+func Delete(householdId uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Expense{}).Where("household_id = ?", householdId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrHouseholdNotEmpty
+		}
+		return deleteHousehold(tx, householdId)
+	})
+}
+
+// CreateInvitation выпускает одноразовое приглашение с указанной ролью
+func CreateInvitation(householdId, createdBy uint, role string) (string, *models.HouseholdInvitation, error) {
+	if !IsValidRole(role) {
+		return "", nil, ErrInvalidRole
+	}
+	rawToken, err := auth.RandomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	invitation := models.HouseholdInvitation{
+		HouseholdID: householdId,
+		TokenHash:   auth.HashToken(rawToken),
+		Role:        role,
+		CreatedBy:   createdBy,
+		ExpiresAt:   time.Now().Add(config.GetConfig().Households.InvitationExpiration),
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		return "", nil, err
+	}
+	return rawToken, &invitation, nil
+}
+
+// PendingInvitations возвращает неиспользованные и не истёкшие приглашения
+func PendingInvitations(householdId uint) ([]models.HouseholdInvitation, error) {
+	var invitations []models.HouseholdInvitation
+	err := database.DB.Where("household_id = ? AND accepted_at IS NULL AND expires_at > ?", householdId, time.Now()).
+		Order("created_at").Find(&invitations).Error
+	return invitations, err
+}
+
+// RevokeInvitation отзывает неиспользованное приглашение; возвращает false, если его нет
+func RevokeInvitation(householdId, invitationId uint) (bool, error) {
+	result := database.DB.Where("id = ? AND household_id = ? AND accepted_at IS NULL", invitationId, householdId).
+		Delete(&models.HouseholdInvitation{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// AcceptInvitation погашает приглашение и добавляет пользователя в домохозяйство
+func AcceptInvitation(rawToken string, userId uint) (*models.HouseholdMember, error) {
+	var member models.HouseholdMember
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var invitation models.HouseholdInvitation
+		err := tx.Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", auth.HashToken(rawToken), time.Now()).
+			First(&invitation).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidInvitation
+			}
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&models.HouseholdMember{}).
+			Where("household_id = ? AND user_id = ?", invitation.HouseholdID, userId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyMember
+		}
+
+		// Приглашение одноразовое: из параллельных запросов его погасит только один
+		now := time.Now()
+		result := tx.Model(&models.HouseholdInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_by": userId})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInvitation
+		}
+
+		member = models.HouseholdMember{
+			HouseholdID: invitation.HouseholdID,
+			UserID:      userId,
+			Role:        invitation.Role,
+		}
+		return tx.Create(&member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// SetMemberRole меняет роль участника, не позволяя оставить домохозяйство без владельца
+func SetMemberRole(householdId, userId uint, role string) error {
+	if !IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		member, err := findMember(tx, householdId, userId)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.HouseholdRoleOwner && role != models.HouseholdRoleOwner {
+			if err := ensureAnotherOwner(tx, householdId); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.HouseholdMember{}).
+			Where("household_id = ? AND user_id = ?", householdId, userId).
+			Update("role", role).Error
+	})
+}
+
+// RemoveMember исключает участника из домохозяйства. Расходы, которые он
+// добавил, остаются в домохозяйстве
+func RemoveMember(householdId, userId uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		member, err := findMember(tx, householdId, userId)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.HouseholdRoleOwner {
+			if err := ensureAnotherOwner(tx, householdId); err != nil {
+				return err
+			}
+		}
+		return tx.Where("household_id = ? AND user_id = ?", householdId, userId).
+			Delete(&models.HouseholdMember{}).Error
+	})
+}
+
+// RemoveUser исключает пользователя из всех домохозяйств при удалении аккаунта.
+// Если он был единственным владельцем, владельцем становится самый давний участник,
+// а домохозяйство без участников удаляется вместе со своими данными
+func RemoveUser(tx *gorm.DB, userId uint) error {
+	var memberships []models.HouseholdMember
+	if err := tx.Where("user_id = ?", userId).Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		var others []models.HouseholdMember
+		err := tx.Where("household_id = ? AND user_id <> ?", membership.HouseholdID, userId).
+			Order("created_at").Find(&others).Error
+		if err != nil {
+			return err
+		}
+
+		if len(others) == 0 {
+			expenseIds := tx.Model(&models.Expense{}).Select("id").Where("household_id = ?", membership.HouseholdID)
+			if err := splits.DeleteShares(tx, expenseIds); err != nil {
+				return err
+			}
+			if err := tx.Where("household_id = ?", membership.HouseholdID).Delete(&models.Expense{}).Error; err != nil {
+				return err
+			}
+			if err := deleteHousehold(tx, membership.HouseholdID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if membership.Role == models.HouseholdRoleOwner && !hasOwner(others) {
+			err := tx.Model(&models.HouseholdMember{}).
+				Where("household_id = ? AND user_id = ?", others[0].HouseholdID, others[0].UserID).
+				Update("role", models.HouseholdRoleOwner).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Where("user_id = ?", userId).Delete(&models.HouseholdMember{}).Error
+}
+
+func findMember(tx *gorm.DB, householdId, userId uint) (*models.HouseholdMember, error) {
+	var member models.HouseholdMember
+	err := tx.Where("household_id = ? AND user_id = ?", householdId, userId).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ensureAnotherOwner проверяет, что у домохозяйства останется владелец,
+// если текущий перестанет им быть
+func ensureAnotherOwner(tx *gorm.DB, householdId uint) error {
+	var owners int64
+	err := tx.Model(&models.HouseholdMember{}).
+		Where("household_id = ? AND role = ?", householdId, models.HouseholdRoleOwner).
+		Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func hasOwner(members []models.HouseholdMember) bool {
+	for _, member := range members {
+		if member.Role == models.HouseholdRoleOwner {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteHousehold(tx *gorm.DB, householdId uint) error {
+	err := tx.Where("budget_id IN (?)", tx.Model(&models.Budget{}).Select("id").Where("household_id = ?", householdId)).
+		Delete(&models.BudgetAlert{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Where("recurring_id IN (?)", tx.Model(&models.RecurringExpense{}).Select("id").Where("household_id = ?", householdId)).
+		Delete(&models.RecurringException{}).Error
+	if err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.Category{},
+		&models.Budget{},
+		&models.RecurringExpense{},
+		&models.Settlement{},
+		&models.HouseholdInvitation{},
+		&models.HouseholdMember{},
+	} {
+		if err := tx.Where("household_id = ?", householdId).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.Household{}, householdId).Error
+}
@@ -0,0 +1,88 @@
+package households
+
+import (
+	"gorm.io/gorm"
+	"project/models"
+)
+
+// memberOf строит подзапрос идентификаторов домохозяйств, в которых состоит
+// пользователь; если роли указаны, учитываются только они
+func memberOf(db *gorm.DB, userId uint, roles ...string) *gorm.DB {
+	query := db.Session(&gorm.Session{NewDB: true}).Model(&models.HouseholdMember{}).
+		Select("household_id").Where("user_id = ?", userId)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	return query
+}
+
+// VisibleExpenses ограничивает запрос расходами, которые видит пользователь:
+// его личными и расходами домохозяйств, в которых он состоит
+func VisibleExpenses(userId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((expenses.household_id IS NULL AND expenses.user_id = ?) OR expenses.household_id IN (?))",
+			userId, memberOf(db, userId))
+	}
+}
+
+// EditableExpenses ограничивает запрос расходами, которые пользователь может
+// изменять: личными и расходами домохозяйств, где он владелец или редактор
+func EditableExpenses(userId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((expenses.household_id IS NULL AND expenses.user_id = ?) OR expenses.household_id IN (?))",
+			userId, memberOf(db, userId, models.HouseholdRoleOwner, models.HouseholdRoleEditor))
+	}
+}
+
+// PersonalExpenses ограничивает запрос личными расходами пользователя
+func PersonalExpenses(userId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expenses.household_id IS NULL AND expenses.user_id = ?", userId)
+	}
+}
+
+// HouseholdExpenses ограничивает запрос расходами одного домохозяйства;
+// членство пользователя проверяется отдельно
+func HouseholdExpenses(householdId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expenses.household_id = ?", householdId)
+	}
+}
+
+// VisibleCategories ограничивает запрос общими категориями, личными категориями
+// пользователя и категориями его домохозяйств
+func VisibleCategories(userId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(categories.owner_id = 0 OR (categories.household_id IS NULL AND categories.owner_id = ?) OR categories.household_id IN (?))",
+			userId, memberOf(db, userId))
+	}
+}
+
+// UsableCategories ограничивает запрос категориями, которые можно назначить
+// расходу: личному — общие и личные, расходу домохозяйства — общие и его собственные
+func UsableCategories(userId uint, householdId *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if householdId != nil {
+			return db.Where("(categories.owner_id = 0 OR categories.household_id = ?)", *householdId)
+		}
+		return db.Where("(categories.owner_id = 0 OR (categories.household_id IS NULL AND categories.owner_id = ?))", userId)
+	}
+}
+
+// VisibleBudgets ограничивает запрос личными бюджетами пользователя и бюджетами
+// домохозяйств, в которых он состоит
+func VisibleBudgets(userId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((budgets.household_id IS NULL AND budgets.user_id = ?) OR budgets.household_id IN (?))",
+			userId, memberOf(db, userId))
+	}
+}
+
+// VisibleRecurringExpenses ограничивает запрос личными правилами повторения
+// пользователя и правилами домохозяйств, в которых он состоит
+func VisibleRecurringExpenses(userId uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((recurring_expenses.household_id IS NULL AND recurring_expenses.user_id = ?) OR recurring_expenses.household_id IN (?))",
+			userId, memberOf(db, userId))
+	}
+}
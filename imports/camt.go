@@ -0,0 +1,224 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"project/money"
+)
+
+// Элементы CAMT.053 сопоставляются по локальным именам, поэтому подходят все
+// версии схемы (camt.053.001.02 и новее), где структура записей совпадает
+
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus — в версиях до 001.08 статус записан текстом, в новых — кодом в Cd
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+// camtParty — имя стороны: в Nm до версии 001.08, в Pty>Nm в новых
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+type camtEntry struct {
+	Amount          camtAmount        `xml:"Amt"`
+	Indicator       string            `xml:"CdtDbtInd"`
+	Reversal        bool              `xml:"RvslInd"`
+	Status          camtStatus        `xml:"Sts"`
+	BookingDate     string            `xml:"BookgDt>Dt"`
+	BookingDateTime string            `xml:"BookgDt>DtTm"`
+	ValueDate       string            `xml:"ValDt>Dt"`
+	Reference       string            `xml:"AcctSvcrRef"`
+	EntryReference  string            `xml:"NtryRef"`
+	Info            string            `xml:"AddtlNtryInf"`
+	Details         []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTransaction struct {
+	Amount        *camtAmount `xml:"Amt"`
+	TxAmount      *camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Indicator     string      `xml:"CdtDbtInd"`
+	Reference     string      `xml:"Refs>AcctSvcrRef"`
+	TransactionID string      `xml:"Refs>TxId"`
+	EndToEndID    string      `xml:"Refs>EndToEndId"`
+	Creditor      camtParty   `xml:"RltdPties>Cdtr"`
+	Remittance    []string    `xml:"RmtInf>Ustrd"`
+	Info          string      `xml:"AddtlTxInf"`
+}
+
+// ParseCAMT разбирает выписку ISO 20022 CAMT.053. Учитываются только проведённые
+// записи (BOOK); сторнирующие записи пропускаются. Запись, объединяющая несколько
+// операций с отдельными суммами, превращается в несколько операций
+func ParseCAMT(data []byte) ([]Transaction, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "utf-8") {
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	var transactions []Transaction
+	var account camtAccount
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch element.Name.Local {
+		case "Stmt":
+			account = camtAccount{}
+		case "Acct":
+			if err := decoder.DecodeElement(&account, &element); err != nil {
+				return nil, err
+			}
+		case "Ntry":
+			line, _ := decoder.InputPos()
+			var entry camtEntry
+			if err := decoder.DecodeElement(&entry, &element); err != nil {
+				return nil, err
+			}
+			entryTransactions, err := camtTransactions(entry, account, line)
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, entryTransactions...)
+		}
+	}
+	return transactions, nil
+}
+
+func camtTransactions(entry camtEntry, account camtAccount, line int) ([]Transaction, error) {
+	status := strings.TrimSpace(entry.Status.Code)
+	if status == "" {
+		status = strings.TrimSpace(entry.Status.Text)
+	}
+	if status != "" && status != "BOOK" || entry.Reversal {
+		return nil, nil
+	}
+
+	dateValue := entry.BookingDate
+	if dateValue == "" {
+		dateValue = entry.BookingDateTime
+	}
+	if dateValue == "" {
+		dateValue = entry.ValueDate
+	}
+	date, err := parseStatementDate(dateValue)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	accountId := account.IBAN
+	if accountId == "" {
+		accountId = account.Other
+	}
+
+	base := Transaction{
+		Line:     line,
+		ID:       firstNonEmpty(entry.Reference, entry.EntryReference),
+		Account:  accountId,
+		Date:     date,
+		Currency: firstNonEmpty(entry.Amount.Currency, account.Currency),
+		Name:     strings.TrimSpace(entry.Info),
+	}
+	amount, err := camtSignedAmount(entry.Amount.Value, entry.Indicator)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	base.Amount = amount
+
+	split := len(entry.Details) > 1
+	for _, details := range entry.Details {
+		if details.Amount == nil && details.TxAmount == nil {
+			split = false
+		}
+	}
+	if !split {
+		if len(entry.Details) == 1 {
+			base = camtApplyDetails(base, entry.Details[0])
+		}
+		return []Transaction{base}, nil
+	}
+
+	transactions := make([]Transaction, 0, len(entry.Details))
+	for i, details := range entry.Details {
+		transaction := camtApplyDetails(base, details)
+		if transaction.ID == base.ID && base.ID != "" {
+			transaction.ID = fmt.Sprintf("%s#%d", base.ID, i+1)
+		}
+		txAmount := details.Amount
+		if txAmount == nil {
+			txAmount = details.TxAmount
+		}
+		amount, err := camtSignedAmount(txAmount.Value, firstNonEmpty(details.Indicator, entry.Indicator))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		transaction.Amount = amount
+		transaction.Currency = firstNonEmpty(txAmount.Currency, transaction.Currency)
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// camtApplyDetails уточняет идентификатор и название операции по её подробностям
+func camtApplyDetails(transaction Transaction, details camtTransaction) Transaction {
+	endToEnd := details.EndToEndID
+	if strings.EqualFold(endToEnd, "NOTPROVIDED") {
+		endToEnd = ""
+	}
+	transaction.ID = firstNonEmpty(details.Reference, details.TransactionID, endToEnd, transaction.ID)
+	name := firstNonEmpty(details.Creditor.Name, details.Creditor.PartyName,
+		strings.Join(details.Remittance, " "), details.Info)
+	if name != "" {
+		transaction.Name = name
+	}
+	return transaction
+}
+
+func camtSignedAmount(value, indicator string) (money.Amount, error) {
+	amount, err := money.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	switch strings.TrimSpace(indicator) {
+	case "DBIT":
+		return -amount, nil
+	case "CRDT":
+		return amount, nil
+	}
+	return 0, fmt.Errorf("invalid credit/debit indicator %q", indicator)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package imports
+
+import (
+	"testing"
+	"time"
+)
+
+const camtStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Acct>
+        <Id><IBAN>DE89370400440532013000</IBAN></Id>
+        <Ccy>EUR</Ccy>
+      </Acct>
+      <Ntry>
+        <Amt Ccy="EUR">42.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-05</Dt></BookgDt>
+        <AcctSvcrRef>REF1</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+            <RltdPties><Cdtr><Nm>Coffee Co</Nm></Cdtr></RltdPties>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">1000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><DtTm>2025-01-10T08:00:00</DtTm></BookgDt>
+        <AddtlNtryInf>Salary</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2025-01-11</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">7.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <RvslInd>true</RvslInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-12</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">30.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <ValDt><Dt>2025-01-15</Dt></ValDt>
+        <AcctSvcrRef>BATCH</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">10.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Ustrd>Invoice</Ustrd><Ustrd>17</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Amt Ccy="EUR">20.00</Amt>
+            <Refs><TxId>TX2</TxId></Refs>
+            <AddtlTxInf>Second</AddtlTxInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+// В версиях 001.08 и новее статус записан кодом, а имя стороны — в Pty
+const camtStatementV8 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Acct><Id><Othr><Id>40817810099910004312</Id></Othr></Id><Ccy>RUB</Ccy></Acct>
+      <Ntry>
+        <Amt>1234.56</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-03-01</Dt></BookgDt>
+        <NtryRef>N1</NtryRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><AcctSvcrRef>R1</AcctSvcrRef></Refs>
+            <RltdPties><Cdtr><Pty><Nm>Магазин</Nm></Pty></Cdtr></RltdPties>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCAMT(t *testing.T) {
+	iban := "DE89370400440532013000"
+	tests := []struct {
+		name string
+		data string
+		want []Transaction
+	}{
+		{
+			name: "001.02",
+			data: camtStatement,
+			want: []Transaction{
+				{Line: 9, ID: "REF1", Account: iban, Date: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+					Amount: -4250, Currency: "EUR", Name: "Coffee Co"},
+				{Line: 22, Account: iban, Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+					Amount: 100000, Currency: "EUR", Name: "Salary"},
+				{Line: 42, ID: "BATCH#1", Account: iban, Date: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+					Amount: -1000, Currency: "EUR", Name: "Invoice 17"},
+				{Line: 42, ID: "TX2", Account: iban, Date: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+					Amount: -2000, Currency: "EUR", Name: "Second"},
+			},
+		},
+		{
+			name: "001.08",
+			data: camtStatementV8,
+			want: []Transaction{
+				{Line: 6, ID: "R1", Account: "40817810099910004312", Date: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+					Amount: -123456, Currency: "RUB", Name: "Магазин"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseCAMT([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTransactions(t, got, test.want)
+		})
+	}
+}
+
+func TestParseCAMTErrors(t *testing.T) {
+	entry := func(amount, indicator, date string) string {
+		return `<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy="EUR">` + amount + `</Amt><CdtDbtInd>` + indicator +
+			`</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>` + date + `</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>`
+	}
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid amount", entry("1.005", "DBIT", "2025-01-01")},
+		{"invalid indicator", entry("1.00", "DEBIT", "2025-01-01")},
+		{"invalid date", entry("1.00", "DBIT", "01.01.2025")},
+		{"unsupported charset", `<?xml version="1.0" encoding="windows-1251"?><Document></Document>`},
+		{"malformed xml", `<Document><Ntry>`},
+	}
+	for _, test := range tests {
+		if _, err := ParseCAMT([]byte(test.data)); err == nil {
+			t.Errorf("%s: error = nil, want error", test.name)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/config"
+	"project/currency"
+	"project/models"
+	"project/money"
+)
+
+// Поля расхода, которым сопоставляются столбцы файла
+const (
+	FieldDate     = "date"
+	FieldName     = "name"
+	FieldAmount   = "amount"
+	FieldCategory = "category"
+	FieldCurrency = "currency"
+)
+
+// Fields — все поля, которые можно сопоставить столбцам
+var Fields = []string{FieldDate, FieldName, FieldAmount, FieldCategory, FieldCurrency}
+
+// CSVOptions описывает формат файла
+type CSVOptions struct {
+	Delimiter rune
+	// Десятичный разделитель сумм: '.' или ','
+	DecimalSeparator rune
+	// Разделитель разрядов; пробелы между разрядами убираются всегда
+	ThousandsSeparator rune
+	// Форматы дат в нотации Go; дата разбирается первым подходящим
+	DateFormats []string
+	HasHeader   bool
+	// Столбец для поля: название из заголовка или номер, начиная с 1.
+	// Поля без столбца берутся из Target
+	Columns map[string]string
+}
+
+// DefaultCSVOptions возвращает формат, в котором сервис сам отдаёт данные
+func DefaultCSVOptions() CSVOptions {
+	columns := make(map[string]string, len(Fields))
+	for _, field := range Fields {
+		columns[field] = field
+	}
+	return CSVOptions{
+		Delimiter:        ',',
+		DecimalSeparator: '.',
+		DateFormats:      []string{"2006-01-02"},
+		HasHeader:        true,
+		Columns:          columns,
+	}
+}
+
+// ParseCSV разбирает файл и проверяет каждую строку. Ошибка возвращается, только
+// если файл нельзя прочитать целиком; ошибки отдельных строк собираются в Result
+func ParseCSV(r io.Reader, options CSVOptions, target Target) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = options.Delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	result := &Result{Expenses: []models.Expense{}, Errors: []RowError{}}
+	var header []string
+	if options.HasHeader {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		header = record
+		// Excel добавляет BOM в начало файла в UTF-8
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns, err := resolveColumns(options.Columns, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := columns[FieldCategory]; !ok && target.DefaultCategoryID == nil {
+		return nil, fmt.Errorf("column for %q is required", FieldCategory)
+	}
+
+	categories, err := newCategoryMatcher(target)
+	if err != nil {
+		return nil, err
+	}
+	maxRows := config.GetConfig().Import.MaxRows
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+		result.Rows++
+		if result.Rows > maxRows {
+			return nil, ErrTooManyRows
+		}
+
+		value := func(field string) string {
+			index, ok := columns[field]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		expense, errs := buildExpense(value, options, target, categories)
+		if len(errs) > 0 {
+			result.Errors = append(result.Errors, RowError{Line: line, Errors: errs})
+			continue
+		}
+		result.Expenses = append(result.Expenses, expense)
+	}
+	return result, nil
+}
+
+// buildExpense проверяет значения строки и собирает из них расход
+func buildExpense(value func(string) string, options CSVOptions, target Target, categories categoryMatcher) (models.Expense, []string) {
+	var errs []string
+	expense := models.Expense{
+		UserID:      target.UserID,
+		HouseholdID: target.HouseholdID,
+		Name:        value(FieldName),
+		Currency:    target.Currency,
+	}
+	if expense.Name == "" {
+		errs = append(errs, "name is required")
+	}
+
+	if raw := value(FieldDate); raw == "" {
+		errs = append(errs, "date is required")
+	} else if date, ok := parseDate(raw, options.DateFormats); ok {
+		expense.Date = date
+	} else {
+		errs = append(errs, fmt.Sprintf("invalid date %q", raw))
+	}
+
+	if raw := value(FieldCategory); raw != "" {
+		if category, ok := categories.match(raw); ok {
+			expense.CategoryID = category.ID
+		} else {
+			errs = append(errs, fmt.Sprintf("category %q not found", raw))
+		}
+	} else if target.DefaultCategoryID != nil {
+		expense.CategoryID = *target.DefaultCategoryID
+	} else {
+		errs = append(errs, "category is required")
+	}
+
+	if raw := value(FieldCurrency); raw != "" {
+		code, err := currency.Normalize(raw)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unsupported currency %q", raw))
+			return expense, errs
+		}
+		expense.Currency = code
+	}
+	if raw := value(FieldAmount); raw == "" {
+		errs = append(errs, "amount is required")
+	} else if amount, err := currency.ParseAmount(normalizeAmount(raw, options), expense.Currency); err != nil {
+		if errors.Is(err, money.ErrNonPositive) {
+			errs = append(errs, "amount must be positive")
+		} else {
+			errs = append(errs, fmt.Sprintf("invalid amount %q", raw))
+		}
+	} else {
+		expense.Amount = amount
+	}
+	return expense, errs
+}
+
+// resolveColumns переводит сопоставление полей в номера столбцов, начиная с 0
+func resolveColumns(mapping map[string]string, header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for field, column := range mapping {
+		if column == "" {
+			continue
+		}
+		if number, err := strconv.Atoi(column); err == nil {
+			if number < 1 {
+				return nil, fmt.Errorf("invalid column number %d for %q", number, field)
+			}
+			columns[field] = number - 1
+			continue
+		}
+		index := -1
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			// Необязательного столбца с названием по умолчанию может не быть
+			if column == field && (field == FieldCategory || field == FieldCurrency) {
+				continue
+			}
+			return nil, fmt.Errorf("column %q for %q not found in header", column, field)
+		}
+		columns[field] = index
+	}
+	for _, field := range []string{FieldDate, FieldName, FieldAmount} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("column for %q is required", field)
+		}
+	}
+	return columns, nil
+}
+
+func parseDate(value string, formats []string) (time.Time, bool) {
+	for _, format := range formats {
+		if date, err := time.Parse(format, value); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// normalizeAmount приводит сумму к виду, который принимает money.Parse: "1 234,50" → "1234.50"
+func normalizeAmount(value string, options CSVOptions) string {
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', options.ThousandsSeparator:
+			return -1
+		}
+		return r
+	}, value)
+	if options.DecimalSeparator != '.' {
+		value = strings.ReplaceAll(value, string(options.DecimalSeparator), ".")
+	}
+	return strings.TrimPrefix(value, "+")
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/money"
+)
+
+func TestNormalizeAmount(t *testing.T) {
+	dot := CSVOptions{DecimalSeparator: '.', ThousandsSeparator: ','}
+	comma := CSVOptions{DecimalSeparator: ',', ThousandsSeparator: '.'}
+	commaSpaces := CSVOptions{DecimalSeparator: ','}
+	apostrophe := CSVOptions{DecimalSeparator: '.', ThousandsSeparator: '\''}
+
+	tests := []struct {
+		value   string
+		options CSVOptions
+		want    string
+		amount  money.Amount
+	}{
+		{"1234.50", dot, "1234.50", 123450},
+		{"1,234.50", dot, "1234.50", 123450},
+		{"1,234,567", dot, "1234567", 123456700},
+		{"+12.30", dot, "12.30", 1230},
+		{"-12.30", dot, "-12.30", -1230},
+		{"1.234,50", comma, "1234.50", 123450},
+		{"12,3", comma, "12.3", 1230},
+		{"1 234,50", commaSpaces, "1234.50", 123450},
+		{"1\u00a0234,50", commaSpaces, "1234.50", 123450},
+		{"1\u202f234,50", commaSpaces, "1234.50", 123450},
+		{"1'234.50", apostrophe, "1234.50", 123450},
+		{" 7 ", dot, "7", 700},
+	}
+	for _, test := range tests {
+		got := normalizeAmount(test.value, test.options)
+		if got != test.want {
+			t.Errorf("normalizeAmount(%q) = %q, want %q", test.value, got, test.want)
+			continue
+		}
+		if amount, err := money.Parse(got); err != nil || amount != test.amount {
+			t.Errorf("money.Parse(%q) = %d, %v, want %d", got, amount, err, test.amount)
+		}
+	}
+
+	// Значения, которые после приведения всё ещё отвергает money.Parse
+	for _, value := range []string{"1.234,50", "12.345", "1e3", "++1"} {
+		if _, err := money.Parse(normalizeAmount(value, dot)); err == nil {
+			t.Errorf("money.Parse(normalizeAmount(%q)) error = nil, want error", value)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	formats := []string{"02.01.2006", "2006-01-02", "01/02/2006"}
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"31.01.2025", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), true},
+		{"2025-01-31", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), true},
+		{"01/31/2025", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), true},
+		{"29.02.2024", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), true},
+		{"29.02.2025", time.Time{}, false},
+		{"31/01/2025", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, test := range tests {
+		got, ok := parseDate(test.value, formats)
+		if ok != test.ok || !got.Equal(test.want) {
+			t.Errorf("parseDate(%q) = %s, %v, want %s, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	header := []string{"Date", " Description ", "Sum", "Category"}
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		header  []string
+		want    map[string]int
+		fails   bool
+	}{
+		{
+			name:    "by name",
+			mapping: map[string]string{FieldDate: "date", FieldName: "description", FieldAmount: "SUM", FieldCategory: "category", FieldCurrency: "currency"},
+			header:  header,
+			want:    map[string]int{FieldDate: 0, FieldName: 1, FieldAmount: 2, FieldCategory: 3},
+		},
+		{
+			name:    "by number",
+			mapping: map[string]string{FieldDate: "3", FieldName: "1", FieldAmount: "2"},
+			want:    map[string]int{FieldDate: 2, FieldName: 0, FieldAmount: 1},
+		},
+		{
+			name:    "empty column ignored",
+			mapping: map[string]string{FieldDate: "1", FieldName: "2", FieldAmount: "3", FieldCategory: ""},
+			want:    map[string]int{FieldDate: 0, FieldName: 1, FieldAmount: 2},
+		},
+		{
+			name:    "missing required",
+			mapping: map[string]string{FieldDate: "1", FieldName: "2"},
+			fails:   true,
+		},
+		{
+			name:    "zero column",
+			mapping: map[string]string{FieldDate: "0", FieldName: "2", FieldAmount: "3"},
+			fails:   true,
+		},
+		{
+			name:    "unknown name",
+			mapping: map[string]string{FieldDate: "date", FieldName: "description", FieldAmount: "amount"},
+			header:  header,
+			fails:   true,
+		},
+	}
+	for _, test := range tests {
+		got, err := resolveColumns(test.mapping, test.header)
+		if (err != nil) != test.fails {
+			t.Errorf("%s: error = %v, want failure %v", test.name, err, test.fails)
+			continue
+		}
+		if !test.fails && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: columns = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBuildExpense(t *testing.T) {
+	defaultCategory := uint(9)
+	categories := categoryMatcher{"food": models.Category{ID: 3, Name: "Food"}}
+	options := CSVOptions{DecimalSeparator: ',', ThousandsSeparator: '.', DateFormats: []string{"02.01.2006"}}
+	target := Target{UserID: 1, Currency: "RUB", DefaultCategoryID: &defaultCategory}
+
+	tests := []struct {
+		name     string
+		row      map[string]string
+		amount   money.Amount
+		currency string
+		category uint
+		errors   []string
+	}{
+		{
+			name:     "valid",
+			row:      map[string]string{FieldDate: "31.01.2025", FieldName: "Хлеб", FieldAmount: "1.234,50", FieldCategory: " FOOD "},
+			amount:   123450,
+			currency: "RUB",
+			category: 3,
+		},
+		{
+			name:     "default category and own currency",
+			row:      map[string]string{FieldDate: "31.01.2025", FieldName: "Bread", FieldAmount: "2,5", FieldCurrency: "usd"},
+			amount:   250,
+			currency: "USD",
+			category: 9,
+		},
+		{
+			name:   "currency without minor units",
+			row:    map[string]string{FieldDate: "31.01.2025", FieldName: "Pan", FieldAmount: "100,50", FieldCurrency: "JPY"},
+			errors: []string{`invalid amount "100,50"`},
+		},
+		{
+			name:   "negative amount",
+			row:    map[string]string{FieldDate: "31.01.2025", FieldName: "Refund", FieldAmount: "-5"},
+			errors: []string{"amount must be positive"},
+		},
+		{
+			name:   "every field invalid",
+			row:    map[string]string{FieldDate: "2025-01-31", FieldAmount: "abc", FieldCategory: "rent"},
+			errors: []string{"name is required", `invalid date "2025-01-31"`, `category "rent" not found`, `invalid amount "abc"`},
+		},
+	}
+	for _, test := range tests {
+		value := func(field string) string { return strings.TrimSpace(test.row[field]) }
+		expense, errs := buildExpense(value, options, target, categories)
+		if !reflect.DeepEqual(errs, test.errors) {
+			t.Errorf("%s: errors = %q, want %q", test.name, errs, test.errors)
+			continue
+		}
+		if len(test.errors) > 0 {
+			continue
+		}
+		if expense.Amount != test.amount || expense.Currency != test.currency || expense.CategoryID != test.category {
+			t.Errorf("%s: expense = %s %s category %d, want %s %s category %d", test.name,
+				expense.Amount, expense.Currency, expense.CategoryID, test.amount, test.currency, test.category)
+		}
+	}
+}
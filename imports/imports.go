@@ -0,0 +1,109 @@
+package imports
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/budgets"
+	"project/database"
+	"project/households"
+	"project/logging"
+	"project/models"
+)
+
+var ErrTooManyRows = errors.New("too many rows in file")
+
+// Target — владелец импортируемых расходов и значения по умолчанию для строк
+type Target struct {
+	UserID      uint
+	HouseholdID *uint
+	// Валюта строк без своей валюты
+	Currency string
+	// Категория строк без категории; nil — категория обязательна
+	DefaultCategoryID *uint
+}
+
+// RowError — ошибки проверки одной строки файла
+type RowError struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+}
+
+// Result — расходы, готовые к записи, и ошибки строк, которые не прошли проверку
+type Result struct {
+	Rows     int              `json:"rows"`
+	Expenses []models.Expense `json:"expenses"`
+	Errors   []RowError       `json:"errors"`
+}
+
+// Commit записывает расходы одной транзакцией: при ошибке не сохраняется ни один,
+// затем проверяет пороги бюджетов. Расходы из выписок, импортированные после
+// разбора файла, пропускаются, а одновременный импорт той же выписки остановит
+// уникальный индекс. Возвращается число записанных расходов
+func Commit(expenses []models.Expense, now time.Time) (int, error) {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		existing, err := existingImports(tx, expenses)
+		if err != nil {
+			return err
+		}
+		fresh := expenses[:0]
+		for _, expense := range expenses {
+			if expense.ImportID == nil || !existing[*expense.ImportID] {
+				fresh = append(fresh, expense)
+			}
+		}
+		expenses = fresh
+		if len(expenses) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&expenses, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Состояние бюджета зависит только от суммы за период, поэтому достаточно
+	// проверить по одному, самому позднему расходу каждой категории
+	latest := map[uint]models.Expense{}
+	for _, expense := range expenses {
+		if current, ok := latest[expense.CategoryID]; !ok || expense.Date.After(current.Date) {
+			latest[expense.CategoryID] = expense
+		}
+	}
+	for _, expense := range latest {
+		if err := budgets.CheckAlerts(expense, now); err != nil {
+			logging.Logger.Error("Failed to check budget alerts", zap.Uint("expense_id", expense.ID), zap.Error(err))
+		}
+	}
+	return len(expenses), nil
+}
+
+// categoryMatcher находит категорию по названию без учёта регистра. Собственная
+// категория пользователя или домохозяйства важнее общей с тем же названием
+type categoryMatcher map[string]models.Category
+
+func newCategoryMatcher(target Target) (categoryMatcher, error) {
+	var categories []models.Category
+	err := database.DB.Scopes(households.UsableCategories(target.UserID, target.HouseholdID)).
+		Order("id").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	matcher := categoryMatcher{}
+	for _, category := range categories {
+		key := strings.ToLower(strings.TrimSpace(category.Name))
+		if existing, ok := matcher[key]; ok && existing.OwnerId != 0 {
+			continue
+		}
+		matcher[key] = category
+	}
+	return matcher, nil
+}
+
+func (m categoryMatcher) match(name string) (models.Category, bool) {
+	category, ok := m[strings.ToLower(strings.TrimSpace(name))]
+	return category, ok
+}
@@ -0,0 +1,118 @@
+package imports
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"project/money"
+)
+
+// ofxTag находит открывающий или закрывающий тег и значение после него. В OFX 1.x
+// (SGML) у простых элементов нет закрывающих тегов, поэтому файл разбирается
+// как последовательность тегов, а не как XML; так же читается и OFX 2.x
+var ofxTag = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+
+// ParseOFX разбирает выписку OFX по счёту (STMTRS) или кредитной карте (CCSTMTRS)
+func ParseOFX(data []byte) ([]Transaction, error) {
+	start := bytes.Index(data, []byte("<OFX>"))
+	if start < 0 {
+		return nil, fmt.Errorf("OFX element not found")
+	}
+
+	var transactions []Transaction
+	var current *Transaction
+	var account, currencyCode, memo, kind string
+	// Внутри ORIGCURRENCY указана исходная валюта, а сумма уже в валюте счёта
+	var original bool
+	line, counted := 1, 0
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		if current.Name == "" {
+			current.Name = memo
+		}
+		if current.Name == "" {
+			current.Name = kind
+		}
+		if current.Date.IsZero() {
+			return fmt.Errorf("line %d: transaction without date", current.Line)
+		}
+		current.Account = account
+		if current.Currency == "" {
+			current.Currency = currencyCode
+		}
+		transactions = append(transactions, *current)
+		current = nil
+		return nil
+	}
+
+	for _, match := range ofxTag.FindAllSubmatchIndex(data[start:], -1) {
+		closing := match[3] > match[2]
+		tag := strings.ToUpper(string(data[start+match[4] : start+match[5]]))
+		value := strings.TrimSpace(html.UnescapeString(string(data[start+match[6] : start+match[7]])))
+
+		if tag == "STMTTRN" {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if !closing {
+				line += bytes.Count(data[counted:start+match[0]], []byte("\n"))
+				counted = start + match[0]
+				current = &Transaction{Line: line}
+				memo, kind = "", ""
+			}
+			continue
+		}
+		if tag == "ORIGCURRENCY" {
+			original = !closing
+			continue
+		}
+		if closing || value == "" {
+			continue
+		}
+		if current == nil {
+			switch tag {
+			case "ACCTID":
+				account = value
+			case "CURDEF":
+				currencyCode = value
+			}
+			continue
+		}
+
+		switch tag {
+		case "FITID":
+			current.ID = value
+		case "DTPOSTED":
+			date, err := parseStatementDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", current.Line, err)
+			}
+			current.Date = date
+		case "TRNAMT":
+			amount, err := money.Parse(strings.TrimPrefix(strings.ReplaceAll(value, ",", "."), "+"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount %q", current.Line, value)
+			}
+			current.Amount = amount
+		case "NAME", "PAYEE":
+			current.Name = value
+		case "MEMO":
+			memo = value
+		case "TRNTYPE":
+			kind = value
+		case "CURSYM":
+			if !original {
+				current.Currency = value
+			}
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
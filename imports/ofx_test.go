@@ -0,0 +1,136 @@
+package imports
+
+import (
+	"testing"
+	"time"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20250201</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>121000248<ACCTID>1234567890<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20250101<DTEND>20250131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250105120000[-5:EST]
+<TRNAMT>-42.50
+<FITID>2025010501
+<NAME>Coffee &amp; Co
+<MEMO>Card 1234
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250110
+<TRNAMT>+1000.00
+<FITID>2025011001
+<MEMO>Salary
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>FEE
+<DTPOSTED>20250131
+<TRNAMT>-3,5
+<FITID>2025013101
+<CURRENCY><CURRATE>1.0<CURSYM>EUR</CURRENCY>
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>EUR</CURDEF>
+        <CCACCTFROM>
+          <ACCTID>4111111111111111</ACCTID>
+        </CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20250203</DTPOSTED>
+            <TRNAMT>-19.99</TRNAMT>
+            <FITID>A1</FITID>
+            <PAYEE>Streaming</PAYEE>
+            <ORIGCURRENCY>
+              <CURRATE>0.92</CURRATE>
+              <CURSYM>USD</CURSYM>
+            </ORIGCURRENCY>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>POS</TRNTYPE>
+            <DTPOSTED>20250204</DTPOSTED>
+            <TRNAMT>-5.00</TRNAMT>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Transaction
+	}{
+		{
+			name: "sgml",
+			data: ofxSGML,
+			want: []Transaction{
+				{Line: 13, ID: "2025010501", Account: "1234567890", Date: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+					Amount: -4250, Currency: "USD", Name: "Coffee & Co"},
+				{Line: 21, ID: "2025011001", Account: "1234567890", Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+					Amount: 100000, Currency: "USD", Name: "Salary"},
+				{Line: 28, ID: "2025013101", Account: "1234567890", Date: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+					Amount: -350, Currency: "EUR", Name: "FEE"},
+			},
+		},
+		{
+			name: "xml",
+			data: ofxXML,
+			want: []Transaction{
+				{Line: 12, ID: "A1", Account: "4111111111111111", Date: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+					Amount: -1999, Currency: "EUR", Name: "Streaming"},
+				{Line: 23, Account: "4111111111111111", Date: time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC),
+					Amount: -500, Currency: "EUR", Name: "POS"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseOFX([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTransactions(t, got, test.want)
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no ofx element", "OFXHEADER:100\n"},
+		{"no date", "<OFX><STMTTRN><TRNAMT>-1.00<FITID>1</STMTTRN></OFX>"},
+		{"invalid date", "<OFX><STMTTRN><DTPOSTED>2025<TRNAMT>-1.00</STMTTRN></OFX>"},
+		{"invalid amount", "<OFX><STMTTRN><DTPOSTED>20250101<TRNAMT>-1.005</STMTTRN></OFX>"},
+	}
+	for _, test := range tests {
+		if _, err := ParseOFX([]byte(test.data)); err == nil {
+			t.Errorf("%s: error = nil, want error", test.name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"project/money"
+)
+
+// qifTransactionTypes — разделы QIF с операциями по счетам; инвестиционные
+// операции и списки категорий пропускаются
+var qifTransactionTypes = map[string]bool{
+	"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true,
+}
+
+// ParseQIF разбирает выписку QIF. В формате нет идентификаторов операций, а формат
+// дат и сумм зависит от программы, которая его выгрузила, поэтому задаётся в options
+func ParseQIF(data []byte, options StatementOptions) ([]Transaction, error) {
+	amountOptions := CSVOptions{DecimalSeparator: options.DecimalSeparator, ThousandsSeparator: ','}
+	if options.DecimalSeparator == ',' {
+		amountOptions.ThousandsSeparator = '.'
+	}
+
+	var transactions []Transaction
+	var account, section string
+	var current Transaction
+	var memo, amount, date string
+	started := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(text)
+			switch {
+			case header == "!account":
+				section = "account"
+			case strings.HasPrefix(header, "!type:"):
+				section = strings.TrimSpace(strings.TrimPrefix(header, "!type:"))
+			}
+			continue
+		}
+		if !started {
+			current = Transaction{Line: line}
+			memo, amount, date = "", "", ""
+			started = true
+		}
+
+		code, value := text[0], strings.TrimSpace(text[1:])
+		if section == "account" {
+			if code == 'N' {
+				account = value
+			}
+			if code == '^' {
+				started = false
+			}
+			continue
+		}
+		if !qifTransactionTypes[section] {
+			if code == '^' {
+				started = false
+			}
+			continue
+		}
+
+		switch code {
+		case 'D':
+			date = strings.ReplaceAll(value, " ", "")
+		case 'T':
+			amount = value
+		case 'U':
+			if amount == "" {
+				amount = value
+			}
+		case 'P':
+			current.Name = value
+		case 'M':
+			memo = value
+		case 'L':
+			// [Счёт] — перевод между счетами, а не категория; после / указан класс
+			category, _, _ := strings.Cut(value, "/")
+			if !strings.HasPrefix(category, "[") {
+				current.Category = category
+			}
+		case '^':
+			started = false
+			if date == "" {
+				return nil, fmt.Errorf("line %d: transaction without date", current.Line)
+			}
+			parsedDate, ok := parseDate(date, options.DateFormats)
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid date %q", current.Line, date)
+			}
+			current.Date = parsedDate
+			parsedAmount, err := money.Parse(normalizeAmount(amount, amountOptions))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount %q", current.Line, amount)
+			}
+			current.Amount = parsedAmount
+			if current.Name == "" {
+				current.Name = memo
+			}
+			current.Account = account
+			transactions = append(transactions, current)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
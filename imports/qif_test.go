@@ -0,0 +1,78 @@
+package imports
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQIF(t *testing.T) {
+	european := StatementOptions{DateFormats: []string{"02.01.2006", "2.1.2006"}, DecimalSeparator: ','}
+	tests := []struct {
+		name    string
+		data    string
+		options StatementOptions
+		want    []Transaction
+	}{
+		{
+			name: "us format",
+			data: "!Type:Bank\r\n" +
+				"D01/31'25\r\nT-1,234.50\r\nPLandlord\r\nLRent/Home\r\n^\r\n" +
+				"D2/1/2025\r\nT250.00\r\nMSalary\r\nL[Savings]\r\n^\r\n",
+			options: DefaultStatementOptions(),
+			want: []Transaction{
+				{Line: 2, Date: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), Amount: -123450, Name: "Landlord", Category: "Rent"},
+				{Line: 7, Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Amount: 25000, Name: "Salary"},
+			},
+		},
+		{
+			name: "comma decimals",
+			data: "\xef\xbb\xbf!Account\nNGirokonto\nTBank\n^\n" +
+				"!Type:Bank\n" +
+				"D31.01.2025\nT-1.234,50\nPSupermarkt\nLLebensmittel\n^\n" +
+				"D 1. 2.2025\nU-12,5\nMKaffee\n^\n",
+			options: european,
+			want: []Transaction{
+				{Line: 6, Account: "Girokonto", Date: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), Amount: -123450, Name: "Supermarkt", Category: "Lebensmittel"},
+				{Line: 11, Account: "Girokonto", Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Amount: -1250, Name: "Kaffee"},
+			},
+		},
+		{
+			name: "investment and category sections skipped",
+			data: "!Type:Cat\nNFood\nE\n^\n" +
+				"!Type:Invst\nD01/02/2025\nNBuy\nT-100.00\n^\n" +
+				"!Type:CCard\nD01/03/2025\nT-9.99\nPShop\n^\n",
+			options: DefaultStatementOptions(),
+			want: []Transaction{
+				{Line: 11, Date: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), Amount: -999, Name: "Shop"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseQIF([]byte(test.data), test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTransactions(t, got, test.want)
+		})
+	}
+}
+
+func TestParseQIFErrors(t *testing.T) {
+	european := StatementOptions{DateFormats: []string{"02.01.2006"}, DecimalSeparator: ','}
+	tests := []struct {
+		name    string
+		data    string
+		options StatementOptions
+	}{
+		{"no date", "!Type:Bank\nT-1.00\n^\n", DefaultStatementOptions()},
+		{"invalid date", "!Type:Bank\nD31.01.2025\nT-1.00\n^\n", DefaultStatementOptions()},
+		{"too many comma decimals", "!Type:Bank\nD31.01.2025\nT-1,005\n^\n", european},
+		{"too many decimals", "!Type:Bank\nD01/31/2025\nT-1.005\n^\n", DefaultStatementOptions()},
+	}
+	for _, test := range tests {
+		if _, err := ParseQIF([]byte(test.data), test.options); err == nil {
+			t.Errorf("%s: error = nil, want error", test.name)
+		}
+	}
+}
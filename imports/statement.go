@@ -0,0 +1,234 @@
+package imports
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"project/config"
+	"project/currency"
+	"project/database"
+	"project/models"
+	"project/money"
+)
+
+// Форматы банковских выписок
+const (
+	FormatOFX  = "ofx"
+	FormatQIF  = "qif"
+	FormatCAMT = "camt053"
+)
+
+var ErrUnknownFormat = errors.New("unknown statement format")
+
+// defaultName — название расхода, если в выписке нет ни получателя, ни назначения платежа
+const defaultName = "Операция по счёту"
+
+// Transaction — операция из банковской выписки
+type Transaction struct {
+	// Строка файла, с которой начинается операция
+	Line int
+	// Идентификатор операции в банке; пусто — банк его не передал
+	ID      string
+	Account string
+	Date    time.Time
+	// Сумма со знаком: списания отрицательны
+	Amount   money.Amount
+	Currency string
+	Name     string
+	// Категория из выписки, если формат её поддерживает
+	Category string
+}
+
+// StatementOptions описывает формат файлов, в которых он не задан однозначно (QIF)
+type StatementOptions struct {
+	DateFormats      []string
+	DecimalSeparator rune
+}
+
+// DefaultStatementOptions возвращает американский формат, в котором QIF выгружает большинство программ
+func DefaultStatementOptions() StatementOptions {
+	return StatementOptions{
+		DateFormats:      []string{"01/02/2006", "1/2/2006", "01/02/06", "1/2/06", "01/02'06", "1/2'06", "2006-01-02"},
+		DecimalSeparator: '.',
+	}
+}
+
+// StatementResult — результат разбора выписки. В Expenses попадают только новые
+// списания: зачисления и уже импортированные операции пропускаются
+type StatementResult struct {
+	Result
+	Skipped    int `json:"skipped"`
+	Duplicates int `json:"duplicates"`
+}
+
+// DetectFormat определяет формат выписки по содержимому
+func DetectFormat(data []byte) (string, error) {
+	head := data[:min(len(data), 4096)]
+	switch {
+	case bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")):
+		return FormatOFX, nil
+	case bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("BkToCstmrStmt")):
+		return FormatCAMT, nil
+	case bytes.HasPrefix(bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n"), []byte("!")):
+		return FormatQIF, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ParseStatement разбирает выписку в заданном формате
+func ParseStatement(format string, data []byte, options StatementOptions) ([]Transaction, error) {
+	switch format {
+	case FormatOFX:
+		return ParseOFX(data)
+	case FormatQIF:
+		return ParseQIF(data, options)
+	case FormatCAMT:
+		return ParseCAMT(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// BuildStatement превращает списания выписки в расходы. Каждому расходу назначается
+// отпечаток операции: по идентификатору из банка, а без него — по счёту, дате, сумме,
+// названию и порядковому номеру среди одинаковых операций выписки. Операции,
+// отпечатки которых уже есть в базе, считаются импортированными ранее
+func BuildStatement(format string, transactions []Transaction, target Target) (*StatementResult, error) {
+	result := &StatementResult{Result: Result{Expenses: []models.Expense{}, Errors: []RowError{}}}
+	if len(transactions) > config.GetConfig().Import.MaxRows {
+		return nil, ErrTooManyRows
+	}
+	categories, err := newCategoryMatcher(target)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := fmt.Sprintf("user:%d", target.UserID)
+	if target.HouseholdID != nil {
+		scope = fmt.Sprintf("household:%d", *target.HouseholdID)
+	}
+	seen := map[string]int{}
+	for _, transaction := range transactions {
+		result.Rows++
+		if transaction.Amount >= 0 {
+			result.Skipped++
+			continue
+		}
+
+		key := strings.Join([]string{scope, format, transaction.Account, transaction.ID}, "|")
+		if transaction.ID == "" {
+			key = strings.Join([]string{scope, format, transaction.Account, transaction.Date.Format("2006-01-02"),
+				transaction.Amount.String(), transaction.Currency, transaction.Name}, "|")
+		}
+		seen[key]++
+		if transaction.ID != "" && seen[key] > 1 {
+			result.Duplicates++
+			continue
+		}
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+		importId := hex.EncodeToString(sum[:])
+
+		expense, errs := buildTransaction(transaction, target, categories)
+		expense.ImportID = &importId
+		if len(errs) > 0 {
+			result.Errors = append(result.Errors, RowError{Line: transaction.Line, Errors: errs})
+			continue
+		}
+		result.Expenses = append(result.Expenses, expense)
+	}
+
+	existing, err := existingImports(database.DB, result.Expenses)
+	if err != nil {
+		return nil, err
+	}
+	fresh := result.Expenses[:0]
+	for _, expense := range result.Expenses {
+		if existing[*expense.ImportID] {
+			result.Duplicates++
+			continue
+		}
+		fresh = append(fresh, expense)
+	}
+	result.Expenses = fresh
+	return result, nil
+}
+
+// buildTransaction проверяет списание и собирает из него расход
+func buildTransaction(transaction Transaction, target Target, categories categoryMatcher) (models.Expense, []string) {
+	var errs []string
+	expense := models.Expense{
+		UserID:      target.UserID,
+		HouseholdID: target.HouseholdID,
+		Name:        transaction.Name,
+		Date:        transaction.Date,
+		Amount:      -transaction.Amount,
+		Currency:    target.Currency,
+	}
+	if expense.Name == "" {
+		expense.Name = defaultName
+	}
+	if transaction.Currency != "" {
+		code, err := currency.Normalize(transaction.Currency)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unsupported currency %q", transaction.Currency))
+		} else {
+			expense.Currency = code
+		}
+	}
+	if currency.Round(expense.Amount, expense.Currency) != expense.Amount {
+		errs = append(errs, fmt.Sprintf("invalid amount %q", expense.Amount))
+	}
+
+	if category, ok := categories.match(transaction.Category); transaction.Category != "" && ok {
+		expense.CategoryID = category.ID
+	} else if target.DefaultCategoryID != nil {
+		expense.CategoryID = *target.DefaultCategoryID
+	} else if transaction.Category != "" {
+		errs = append(errs, fmt.Sprintf("category %q not found", transaction.Category))
+	} else {
+		errs = append(errs, "category is required")
+	}
+	return expense, errs
+}
+
+// existingImports возвращает отпечатки расходов, которые уже есть в базе
+func existingImports(tx *gorm.DB, expenses []models.Expense) (map[string]bool, error) {
+	existing := map[string]bool{}
+	var ids []string
+	for _, expense := range expenses {
+		if expense.ImportID != nil {
+			ids = append(ids, *expense.ImportID)
+		}
+	}
+	for start := 0; start < len(ids); start += 1000 {
+		var found []string
+		err := tx.Model(&models.Expense{}).
+			Where("import_id IN ?", ids[start:min(start+1000, len(ids))]).Pluck("import_id", &found).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+// parseStatementDate разбирает дату в начале значения вида 20250131, 20250131120000[-5:EST] или 2025-01-31T12:00:00
+func parseStatementDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, format := range []string{"20060102", "2006-01-02"} {
+		if len(value) < len(format) {
+			continue
+		}
+		if date, err := time.Parse(format, value[:len(format)]); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
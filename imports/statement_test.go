@@ -0,0 +1,73 @@
+package imports
+
+import (
+	"testing"
+	"time"
+)
+
+// checkTransactions сравнивает разобранные операции с ожидаемыми
+func checkTransactions(t *testing.T, got, want []Transaction) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d transactions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) {
+			t.Errorf("transaction %d: date = %s, want %s", i, got[i].Date, want[i].Date)
+		}
+		gotDate, wantDate := got[i].Date, want[i].Date
+		got[i].Date, want[i].Date = time.Time{}, time.Time{}
+		if got[i] != want[i] {
+			t.Errorf("transaction %d = %+v, want %+v", i, got[i], want[i])
+		}
+		got[i].Date, want[i].Date = gotDate, wantDate
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"ofx sgml", "OFXHEADER:100\nDATA:OFXSGML\n\n<OFX>", FormatOFX},
+		{"ofx xml", `<?xml version="1.0"?><?OFX OFXHEADER="200"?><OFX>`, FormatOFX},
+		{"camt", `<?xml version="1.0"?><Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`, FormatCAMT},
+		{"camt without namespace", `<Document><BkToCstmrStmt>`, FormatCAMT},
+		{"qif", "!Type:Bank\nD01/31/2025\n", FormatQIF},
+		{"qif with bom", "\xef\xbb\xbf\r\n!Account\n", FormatQIF},
+		{"csv", "date,name,amount\n", ""},
+		{"empty", "", ""},
+	}
+	for _, test := range tests {
+		got, err := DetectFormat([]byte(test.data))
+		if got != test.want || (err != nil) != (test.want == "") {
+			t.Errorf("%s: DetectFormat = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestParseStatementDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		fails bool
+	}{
+		{"20250131", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"20250131120000", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"20250131235959.000[-5:EST]", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{" 2025-01-31 ", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"2025-01-31T12:00:00+01:00", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"20240229", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), false},
+		{"20250229", time.Time{}, true},
+		{"2025013", time.Time{}, true},
+		{"31.01.2025", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, test := range tests {
+		got, err := parseStatementDate(test.value)
+		if (err != nil) != test.fails || !got.Equal(test.want) {
+			t.Errorf("parseStatementDate(%q) = %s, %v, want %s", test.value, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"time"
+
+	"project/config"
+)
+
+// Message — простое текстовое письмо
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма пользователям
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default — отправитель, выбранный в конфигурации при вызове Setup
+var Default Mailer
+
+// Setup создаёт отправителя согласно секции mail конфигурации
+func Setup() error {
+	mailConfig := config.GetConfig().Mail
+	switch mailConfig.Driver {
+	case "smtp":
+		Default = NewSMTPMailer(mailConfig.From, mailConfig.SMTP.Host, mailConfig.SMTP.Port,
+			mailConfig.SMTP.Username, mailConfig.SMTP.Password)
+	case "outbox", "":
+		outbox, err := NewOutboxMailer(mailConfig.From, mailConfig.OutboxDir)
+		if err != nil {
+			return err
+		}
+		Default = outbox
+	default:
+		return fmt.Errorf("unknown mail driver %q", mailConfig.Driver)
+	}
+	return nil
+}
+
+// Send отправляет письмо через отправителя по умолчанию
+func Send(msg Message) error {
+	if Default == nil {
+		return fmt.Errorf("mailer is not configured")
+	}
+	return Default.Send(msg)
+}
+
+func render(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// OutboxMailer складывает письма файлами .eml в каталог — для разработки и тестов
+type OutboxMailer struct {
+	from    string
+	dir     string
+	counter atomic.Uint64
+}
+
+func NewOutboxMailer(from, dir string) (*OutboxMailer, error) {
+	if dir == "" {
+		dir = "outbox"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &OutboxMailer{from: from, dir: dir}, nil
+}
+
+func (m *OutboxMailer) Send(msg Message) error {
+	recipient := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, msg.To)
+	name := fmt.Sprintf("%s-%d-%s.eml", time.Now().Format("20060102T150405"), m.counter.Add(1), recipient)
+	return os.WriteFile(filepath.Join(m.dir, name), render(m.from, msg), 0o644)
+}
@@ -6,16 +6,27 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"project/account"
+	"project/auth"
 	"project/config"
+	"project/currency"
 	"project/database"
 	"project/logging"
+	"project/mail"
 	"project/models"
+	"project/recurring"
 	"project/routes"
 	"time"
+	// Часовые пояса пользователей не должны зависеть от наличия tzdata в системе
+	_ "time/tzdata"
 )
 
 func main() {
 	config.LoadConfig("config.yaml")
+	if err := auth.LoadKeys(); err != nil {
+		logging.Logger.Fatal("Could not load jwt keys: ", zap.Error(err))
+	}
+
 	dbconnect, err := database.ConnectDB()
 	if err != nil {
 		panic("could not connect to db")
@@ -23,6 +34,24 @@ func main() {
 	logging.Logger.Info("Connection is successful")
 
 	addDefaultCategories(dbconnect)
+	promoteAdmins(dbconnect)
+
+	if err := mail.Setup(); err != nil {
+		logging.Logger.Fatal("Could not configure mailer: ", zap.Error(err))
+	}
+
+	if err := auth.StartRevocationStore(); err != nil {
+		logging.Logger.Fatal("Could not load revoked tokens: ", zap.Error(err))
+	}
+
+	auth.StartLoginThrottleCleanup()
+	account.StartPurger()
+
+	if err := currency.Setup(); err != nil {
+		logging.Logger.Fatal("Could not configure currencies: ", zap.Error(err))
+	}
+	currency.StartRateSync()
+	recurring.StartScheduler()
 
 	port := config.GetConfig().Server.Port
 	timeout := config.GetConfig().Server.Timeout
@@ -57,3 +86,20 @@ func addDefaultCategories(db *gorm.DB) {
 		}
 	}
 }
+
+func promoteAdmins(db *gorm.DB) {
+	emails := config.GetConfig().Admin.Emails
+	if len(emails) == 0 {
+		return
+	}
+	result := db.Model(&models.User{}).
+		Where("email IN ? AND role <> ?", emails, models.RoleAdmin).
+		Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		logging.Logger.Error("Failed to promote admins", zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected > 0 {
+		logging.Logger.Info("Admins promoted", zap.Int64("count", result.RowsAffected))
+	}
+}
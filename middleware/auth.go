@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"project/auth"
+	"project/database"
+	"project/logging"
+	"project/models"
+)
+
+// Ключи, под которыми данные аутентификации хранятся в контексте запроса
+const (
+	userLocalsKey     = "user"
+	sessionLocalsKey  = "session_id"
+	claimsLocalsKey   = "claims"
+	apiTokenLocalsKey = "api_token"
+)
+
+// Protected проверяет JWT из cookie "jwt" или заголовка Authorization: Bearer
+// и сохраняет пользователя в контексте запроса. Персональные токены доступа
+// принимаются, только если маршрут перечисляет нужные области доступа и токен
+// выдан на все из них; без scopes маршрут доступен только в рамках сессии
+func Protected(scopes ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		tokenString := TokenFromRequest(c)
+		if tokenString == "" {
+			return unauthorized(c)
+		}
+
+		var userId uint
+		var claims *auth.Claims
+		var apiToken *models.APIToken
+		if auth.IsAPIToken(tokenString) {
+			if len(scopes) == 0 {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Personal access tokens are not allowed for this endpoint",
+				})
+			}
+			var err error
+			apiToken, err = auth.AuthenticateAPIToken(tokenString)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidAPIToken) {
+					return unauthorized(c)
+				}
+				logging.Logger.Error("Database error:", zap.Error(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Error retrieving token",
+				})
+			}
+			for _, scope := range scopes {
+				if !auth.HasScope(*apiToken, scope) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error": "Insufficient scope",
+						"scope": scope,
+					})
+				}
+			}
+			userId = apiToken.UserID
+		} else {
+			var ok bool
+			var err error
+			claims, ok, err = checkSessionToken(tokenString)
+			if err != nil {
+				logging.Logger.Error("Database error:", zap.Error(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Error retrieving session",
+				})
+			}
+			if !ok {
+				return unauthorized(c)
+			}
+			userId, _ = claims.UserID()
+		}
+
+		var user models.User
+		if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return unauthorized(c)
+			}
+			logging.Logger.Error("Database error:", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Error retrieving user",
+			})
+		}
+
+		if user.DisabledAt != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Account is disabled",
+			})
+		}
+
+		if claims != nil {
+			if claims.TokenVersion != user.TokenVersion {
+				return unauthorized(c)
+			}
+			c.Locals(sessionLocalsKey, claims.SessionID)
+			c.Locals(claimsLocalsKey, claims)
+		} else {
+			c.Locals(apiTokenLocalsKey, apiToken)
+		}
+		c.Locals(userLocalsKey, user)
+		return c.Next()
+	}
+}
+
+// CurrentUser возвращает пользователя, сохранённого middleware Protected
+func CurrentUser(c fiber.Ctx) models.User {
+	user, _ := c.Locals(userLocalsKey).(models.User)
+	return user
+}
+
+// CurrentUserID возвращает идентификатор пользователя, сохранённого middleware Protected
+func CurrentUserID(c fiber.Ctx) uint {
+	return CurrentUser(c).ID
+}
+
+// CurrentSessionID возвращает идентификатор сессии, к которой относится access-токен;
+// для запросов по персональному токену возвращает 0
+func CurrentSessionID(c fiber.Ctx) uint {
+	sessionId, _ := c.Locals(sessionLocalsKey).(uint)
+	return sessionId
+}
+
+// CurrentClaims возвращает claims проверенного access-токена
+func CurrentClaims(c fiber.Ctx) *auth.Claims {
+	claims, _ := c.Locals(claimsLocalsKey).(*auth.Claims)
+	return claims
+}
+
+// CurrentAPIToken возвращает персональный токен, по которому выполнен запрос
+func CurrentAPIToken(c fiber.Ctx) *models.APIToken {
+	token, _ := c.Locals(apiTokenLocalsKey).(*models.APIToken)
+	return token
+}
+
+// TokenFromRequest извлекает access-токен из заголовка Authorization или cookie "jwt"
+func TokenFromRequest(c fiber.Ctx) string {
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		const prefix = "Bearer "
+		if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+			return strings.TrimSpace(header[len(prefix):])
+		}
+		return ""
+	}
+	return c.Cookies("jwt")
+}
+
+// checkSessionToken проверяет подпись access-токена, его отзыв и состояние сессии
+func checkSessionToken(tokenString string) (*auth.Claims, bool, error) {
+	claims, err := auth.ParseAccessToken(tokenString)
+	if err != nil {
+		logging.Logger.Warn("Invalid token", zap.Error(err))
+		return nil, false, nil
+	}
+	userId, err := claims.UserID()
+	if err != nil || claims.SessionID == 0 || auth.IsTokenRevoked(claims.ID) {
+		return nil, false, nil
+	}
+
+	active, err := auth.IsSessionActive(userId, claims.SessionID)
+	if err != nil || !active {
+		return nil, false, err
+	}
+	return claims, true, nil
+}
+
+func unauthorized(c fiber.Ctx) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "Unauthorized",
+	})
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/limiter"
+	"project/config"
+)
+
+// RateLimit ограничивает число запросов с одного IP-адреса согласно секции
+// rate_limit конфигурации
+func RateLimit() fiber.Handler {
+	rateConfig := config.GetConfig().RateLimit
+	return RateLimitWindow(rateConfig.Max, rateConfig.Window)
+}
+
+// RateLimitWindow ограничивает число запросов с одного IP-адреса в скользящем окне window
+func RateLimitWindow(maxRequests int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:               maxRequests,
+		Expiration:        window,
+		LimiterMiddleware: limiter.SlidingWindow{},
+		LimitReached: func(c fiber.Ctx) error {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(window.Seconds())))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests, try again later",
+			})
+		},
+	})
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequireRole пропускает только пользователей с одной из перечисленных ролей.
+// Должен стоять после Protected
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !slices.Contains(roles, CurrentUser(c).Role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"project/config"
+)
+
+// RequireVerifiedEmail отклоняет запросы пользователей с неподтверждённой почтой,
+// если это включено в конфигурации. Должен стоять после Protected
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if config.GetConfig().EmailVerification.RequiredForExpenses && !CurrentUser(c).EmailVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Email is not verified",
+			})
+		}
+		return c.Next()
+	}
+}
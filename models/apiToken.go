@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// APIToken — персональный токен доступа для скриптов и интеграций; хранится только его хеш
+type APIToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"token_id"`
+	UserID    uint   `gorm:"not null;index" json:"-"`
+	User      User   `gorm:"foreignKey:UserID" json:"-"`
+	Name      string `gorm:"not null" json:"name"`
+	TokenHash string `gorm:"uniqueIndex;not null" json:"-"`
+	// Первые символы токена, по которым пользователь узнаёт его в списке
+	Prefix string `gorm:"not null" json:"prefix"`
+	// Области доступа через пробел, например "expenses:read expenses:write"
+	Scopes     string     `gorm:"not null" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"project/money"
+)
+
+// Периоды бюджетов; неделя начинается с понедельника
+const (
+	BudgetPeriodWeek  = "week"
+	BudgetPeriodMonth = "month"
+	BudgetPeriodYear  = "year"
+)
+
+// Budget — лимит расходов за период по категории или, без категории, по всем расходам
+type Budget struct {
+	ID     uint `gorm:"primaryKey;autoIncrement" json:"budget_id"`
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	// Бюджет домохозяйства учитывает его расходы; nil — личные расходы пользователя
+	HouseholdID *uint        `gorm:"index" json:"household_id"`
+	CategoryID  *uint        `json:"category_id"`
+	Period      string       `gorm:"not null" json:"period"`
+	Amount      money.Amount `gorm:"type:bigint;not null" json:"amount"`
+	Currency    string       `gorm:"size:3;not null;default:'RUB'" json:"currency"`
+	// Неизрасходованный остаток переносится на следующие периоды, начиная с периода StartDate
+	Rollover  bool      `gorm:"not null;default:false" json:"rollover"`
+	StartDate time.Time `gorm:"type:date;not null" json:"start_date"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -5,6 +5,8 @@ type Category struct {
 	Name        string `gorm:"not null" json:"name"`
 	Description string `gorm:"" json:"description"`
 	OwnerId     uint   `gorm:"foreignKey:UserID" json:"-"`
+	// Категория домохозяйства доступна всем его участникам
+	HouseholdID *uint `gorm:"index" json:"household_id"`
 }
 
 var DefaultCategories = []Category{
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"project/money"
+)
+
+// ExchangeRate — курс на дату: за единицу валюты Base дают Rate единиц валюты Quote
+type ExchangeRate struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	Date      time.Time  `gorm:"type:date;not null;uniqueIndex:idx_exchange_rate" json:"date"`
+	Base      string     `gorm:"size:3;not null;uniqueIndex:idx_exchange_rate" json:"base"`
+	Quote     string     `gorm:"size:3;not null;uniqueIndex:idx_exchange_rate" json:"quote"`
+	Rate      money.Rate `gorm:"type:numeric(24,10);not null" json:"rate"`
+	Source    string     `json:"source"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -2,15 +2,30 @@ package models
 
 import (
 	"time"
+
+	"project/money"
 )
 
 type Expense struct {
-	ID         uint      `gorm:"primaryKey;autoIncrement" json:"expense_id"`
-	Name       string    `gorm:"not null" json:"name"`
-	UserID     uint      `gorm:"not null" json:"-"`
-	User       User      `gorm:"foreignKey:UserID" json:"-"`
-	CategoryID uint      `gorm:"not null" json:"category_id"`
-	Category   Category  `gorm:"foreignKey:CategoryID" json:"-"`
-	Amount     float64   `gorm:"not null" json:"amount"`
-	Date       time.Time `gorm:"not null" json:"date"`
+	ID         uint         `gorm:"primaryKey;autoIncrement" json:"expense_id"`
+	Name       string       `gorm:"not null" json:"name"`
+	UserID     uint         `gorm:"not null;index:idx_expenses_user_date,priority:1" json:"-"`
+	User       User         `gorm:"foreignKey:UserID" json:"-"`
+	CategoryID uint         `gorm:"not null" json:"category_id"`
+	Category   Category     `gorm:"foreignKey:CategoryID" json:"-"`
+	Amount     money.Amount `gorm:"type:bigint;not null" json:"amount"`
+	Date       time.Time    `gorm:"not null;index:idx_expenses_user_date,priority:2;index:idx_expenses_household_date,priority:2" json:"date"`
+	// Расход домохозяйства виден всем его участникам; nil — личный расход
+	HouseholdID *uint `gorm:"index:idx_expenses_household_date,priority:1" json:"household_id"`
+	// Код валюты ISO 4217
+	Currency string `gorm:"size:3;not null;default:'RUB'" json:"currency"`
+	// Способ разделения расхода между участниками; пусто — расход не разделён
+	SplitMethod string `json:"split_method,omitempty"`
+	// Правило, по которому создан расход, и дата повторения; пара уникальна,
+	// поэтому одно повторение не может быть создано дважды
+	RecurringID    *uint      `gorm:"uniqueIndex:idx_expense_occurrence" json:"recurring_id,omitempty"`
+	OccurrenceDate *time.Time `gorm:"type:date;uniqueIndex:idx_expense_occurrence" json:"-"`
+	// Отпечаток операции банковской выписки, из которой импортирован расход.
+	// Уникален, поэтому повторный импорт пересекающейся выписки не дублирует расходы
+	ImportID *string `gorm:"size:64;uniqueIndex:idx_expense_import" json:"-"`
 }
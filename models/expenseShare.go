@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"project/money"
+)
+
+// Способы разделения расхода между участниками
+const (
+	SplitEqual   = "equal"
+	SplitExact   = "exact"
+	SplitPercent = "percent"
+)
+
+// ExpenseShare — доля участника домохозяйства в расходе, который оплатил
+// автор расхода
+type ExpenseShare struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement" json:"-"`
+	ExpenseID uint         `gorm:"not null;uniqueIndex:idx_expense_share" json:"-"`
+	Expense   Expense      `gorm:"foreignKey:ExpenseID" json:"-"`
+	UserID    uint         `gorm:"not null;uniqueIndex:idx_expense_share" json:"user_id"`
+	Amount    money.Amount `gorm:"type:bigint;not null" json:"amount"`
+	// Процент от суммы расхода; заполняется только для способа percent
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// Settlement — перевод, которым один участник погасил долг другому
+type Settlement struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement" json:"settlement_id"`
+	HouseholdID uint         `gorm:"not null;index" json:"household_id"`
+	Household   Household    `gorm:"foreignKey:HouseholdID" json:"-"`
+	FromUserID  uint         `gorm:"not null" json:"from_user_id"`
+	ToUserID    uint         `gorm:"not null" json:"to_user_id"`
+	Amount      money.Amount `gorm:"type:bigint;not null" json:"amount"`
+	Currency    string       `gorm:"size:3;not null;default:'RUB'" json:"currency"`
+	Date        time.Time    `gorm:"not null" json:"date"`
+	Note        string       `json:"note"`
+	CreatedBy   uint         `gorm:"not null" json:"-"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
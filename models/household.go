@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Роли участников домохозяйства
+const (
+	HouseholdRoleOwner  = "owner"
+	HouseholdRoleEditor = "editor"
+	HouseholdRoleViewer = "viewer"
+)
+
+// Household — общий бюджет нескольких пользователей
+type Household struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement" json:"household_id"`
+	Name string `gorm:"not null" json:"name"`
+	// Валюта, в которой считаются балансы и погашения между участниками
+	Currency  string    `gorm:"size:3;not null;default:'RUB'" json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type HouseholdMember struct {
+	HouseholdID uint      `gorm:"primaryKey" json:"household_id"`
+	Household   Household `gorm:"foreignKey:HouseholdID" json:"-"`
+	UserID      uint      `gorm:"primaryKey;index" json:"user_id"`
+	User        User      `gorm:"foreignKey:UserID" json:"-"`
+	Role        string    `gorm:"not null" json:"role"`
+	CreatedAt   time.Time `json:"joined_at"`
+}
+
+// HouseholdInvitation — приглашение по ссылке; хранится только хеш токена
+type HouseholdInvitation struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"invitation_id"`
+	HouseholdID uint       `gorm:"not null;index" json:"household_id"`
+	Household   Household  `gorm:"foreignKey:HouseholdID" json:"-"`
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	Role        string     `gorm:"not null" json:"role"`
+	CreatedBy   uint       `gorm:"not null" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	AcceptedBy  *uint      `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginThrottle — счётчик неудачных попыток входа для аккаунта или IP-адреса
+type LoginThrottle struct {
+	// "email:<адрес>" или "ip:<адрес>"
+	Key           string `gorm:"primaryKey"`
+	Failures      int    `gorm:"not null;default:0"`
+	BlockedUntil  *time.Time
+	LastFailureAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Виды уведомлений
+const (
+	NotificationBudgetWarning  = "budget_warning"
+	NotificationBudgetExceeded = "budget_exceeded"
+)
+
+// Каналы доставки уведомлений
+const (
+	ChannelInbox   = "inbox"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Notification — уведомление во входящих пользователя
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"notification_id"`
+	UserID    uint       `gorm:"not null;index" json:"-"`
+	Kind      string     `gorm:"not null" json:"kind"`
+	Title     string     `gorm:"not null" json:"title"`
+	Body      string     `gorm:"not null" json:"body"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference — каналы, по которым пользователь получает уведомления одного вида
+type NotificationPreference struct {
+	UserID uint   `gorm:"primaryKey"`
+	Kind   string `gorm:"primaryKey"`
+	// Каналы через запятую; пустая строка отключает уведомления этого вида
+	Channels string `gorm:"not null"`
+}
+
+// NotificationWebhook — адрес, на который пользователь получает уведомления канала webhook
+type NotificationWebhook struct {
+	UserID uint   `gorm:"primaryKey" json:"-"`
+	URL    string `gorm:"not null" json:"url"`
+	// Ключ подписи тела запроса HMAC-SHA256; показывается только при создании
+	Secret    string    `gorm:"not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BudgetAlert отмечает сработавший порог бюджета, чтобы он срабатывал один раз за период
+type BudgetAlert struct {
+	BudgetID    uint      `gorm:"primaryKey"`
+	Threshold   int       `gorm:"primaryKey"`
+	PeriodStart time.Time `gorm:"primaryKey;type:date"`
+	CreatedAt   time.Time
+}
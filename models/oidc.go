@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// UserIdentity связывает пользователя с учётной записью внешнего OIDC-провайдера
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"identity_id"`
+	UserID    uint      `gorm:"not null;index" json:"-"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCState — незавершённый вход через OIDC-провайдера
+type OIDCState struct {
+	State        string    `gorm:"primaryKey"`
+	Provider     string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
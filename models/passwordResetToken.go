@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordResetToken — одноразовый токен сброса пароля; хранится только его хеш
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index"`
+	User      User      `gorm:"foreignKey:UserID"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
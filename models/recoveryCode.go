@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RecoveryCode — одноразовый код восстановления доступа при утере устройства с TOTP
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    uint   `gorm:"not null;index"`
+	User      User   `gorm:"foreignKey:UserID"`
+	CodeHash  string `gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
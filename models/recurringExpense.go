@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"project/money"
+)
+
+// Частоты повторения расходов
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+// RecurringExpense — правило, по которому расход создаётся повторно: каждые Interval
+// дней, недель, месяцев или лет начиная с StartDate и не позже EndDate. Для дня месяца,
+// которого нет в коротком месяце, расход создаётся в последний день месяца
+type RecurringExpense struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement" json:"recurring_id"`
+	UserID      uint         `gorm:"not null;index" json:"user_id"`
+	HouseholdID *uint        `gorm:"index" json:"household_id"`
+	CategoryID  uint         `gorm:"not null" json:"category_id"`
+	Name        string       `gorm:"not null" json:"name"`
+	Amount      money.Amount `gorm:"type:bigint;not null" json:"amount"`
+	Currency    string       `gorm:"size:3;not null;default:'RUB'" json:"currency"`
+	Frequency   string       `gorm:"not null" json:"frequency"`
+	Interval    int          `gorm:"not null;default:1" json:"interval"`
+	StartDate   time.Time    `gorm:"type:date;not null" json:"start_date"`
+	EndDate     *time.Time   `gorm:"type:date" json:"end_date"`
+	// Номер и дата следующего повторения, которое ещё не создано; nil — повторений больше нет
+	NextIndex int        `gorm:"not null;default:0" json:"-"`
+	NextDate  *time.Time `gorm:"type:date;index" json:"next_date"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RecurringException — пропуск или изменение одного повторения до его создания
+type RecurringException struct {
+	RecurringID uint      `gorm:"primaryKey" json:"-"`
+	Date        time.Time `gorm:"primaryKey;type:date" json:"date"`
+	Skip        bool      `gorm:"not null;default:false" json:"skip"`
+	// Непустые поля заменяют поля правила в этом повторении
+	Name       string        `json:"name,omitempty"`
+	Amount     *money.Amount `gorm:"type:bigint" json:"amount,omitempty"`
+	CategoryID *uint         `json:"category_id,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RevokedToken — отозванный до истечения срока access-токен
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Session — устройство, на котором выполнен вход; все refresh-токены одной
+// цепочки ротации принадлежат одной сессии
+type Session struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"session_id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	SessionID uint      `gorm:"not null;index"`
+	Session   Session   `gorm:"foreignKey:SessionID"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -1,6 +1,13 @@
 package models
 
+import "project/money"
+
 type SumExpense struct {
-	Sum      float64 `json:"sum"`
-	Category string  `json:"category"`
+	CategoryID uint         `json:"category_id"`
+	Sum        money.Amount `json:"sum"`
+	Category   string       `json:"category"`
+	// Число расходов в категории
+	Count int64 `json:"count"`
+	// Доля суммы категории в итоге отчёта, в процентах
+	Share float64 `json:"share"`
 }
@@ -1,8 +1,35 @@
 package models
 
+import "time"
+
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID       uint   `gorm:"primaryKey;autoIncrement" json:"user_id"`
-	Username string `gorm:"unique;not null" json:"username"`
-	Email    string `gorm:"unique;not null" json:"email"`
-	Password string `gorm:"not null" json:"-"`
+	ID            uint   `gorm:"primaryKey;autoIncrement" json:"user_id"`
+	Username      string `gorm:"unique;not null" json:"username"`
+	Email         string `gorm:"unique;not null" json:"email"`
+	Password      string `gorm:"not null" json:"-"`
+	EmailVerified bool   `gorm:"not null;default:false" json:"email_verified"`
+	Role          string `gorm:"not null;default:'user'" json:"role"`
+	// Отключённый администратором пользователь не может войти
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// Время последней отправки письма подтверждения, для ограничения повторной отправки
+	VerificationSentAt *time.Time `json:"-"`
+	// Секрет TOTP; до подтверждения настройки TOTPEnabled остаётся false
+	TOTPSecret  string `json:"-"`
+	TOTPEnabled bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	// Шаг последнего принятого кода TOTP, защищает от повторного использования кода
+	TOTPLastStep int64 `gorm:"not null;default:0" json:"-"`
+	// Момент окончательного удаления аккаунта; до него удаление можно отменить
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// Увеличивается при выходе со всех устройств, делая недействительными ранее выданные токены
+	TokenVersion uint `gorm:"not null;default:0" json:"-"`
+	// Валюта, в которую пересчитываются суммы в отчётах пользователя
+	BaseCurrency string `gorm:"size:3;not null;default:'RUB'" json:"base_currency"`
+	// Часовой пояс IANA, в котором определяются границы периодов в отчётах
+	Timezone string `gorm:"not null;default:'UTC'" json:"timezone"`
 }
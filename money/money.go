@@ -0,0 +1,164 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale — число знаков после запятой. Суммы хранятся целым числом сотых долей
+// единицы валюты, поэтому сложение не накапливает ошибок округления
+const Scale = 2
+
+const unit = 100
+
+// maxWholeDigits ограничивает сумму 10^13 единиц валюты, оставляя запас
+// до переполнения int64 при суммировании большого числа расходов
+const maxWholeDigits = 13
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrNonPositive   = errors.New("amount must be positive")
+)
+
+// Amount — денежная сумма в сотых долях единицы валюты (копейках, центах).
+// В JSON передаётся строкой "123.45", в базе хранится как BIGINT
+type Amount int64
+
+// Parse разбирает десятичную запись суммы вида "123", "123.4" или "-123.45".
+// Экспоненты, разделители разрядов, NaN и более Scale знаков после точки отвергаются
+func Parse(s string) (Amount, error) {
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !isDigits(whole) || len(whole) > maxWholeDigits {
+		return 0, ErrInvalidAmount
+	}
+	if hasFrac && (!isDigits(frac) || len(frac) > Scale) {
+		return 0, ErrInvalidAmount
+	}
+
+	wholeValue, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	frac += strings.Repeat("0", Scale-len(frac))
+	fracValue, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+
+	amount := Amount(wholeValue*unit + fracValue)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// ParsePositive разбирает сумму и проверяет, что она больше нуля
+func ParsePositive(s string) (Amount, error) {
+	amount, err := Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	if amount <= 0 {
+		return 0, ErrNonPositive
+	}
+	return amount, nil
+}
+
+// FromMinor создаёт сумму из числа сотых долей
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// Minor возвращает сумму в сотых долях
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) String() string {
+	sign := ""
+	value := int64(a)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/unit, value%unit)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(a.String())), nil
+}
+
+// UnmarshalJSON принимает как строку, так и числовой литерал; литерал
+// разбирается как текст, без промежуточного float64
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	amount, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
+
+// Scan читает сумму из BIGINT-колонки, а также результат SUM(), который
+// PostgreSQL возвращает как NUMERIC
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = Amount(v)
+	case float64:
+		*a = Amount(math.Round(v))
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", value)
+	}
+	return nil
+}
+
+func (a *Amount) scanString(s string) error {
+	if minor, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*a = Amount(minor)
+		return nil
+	}
+	minor, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("money: cannot scan %q", s)
+	}
+	*a = Amount(math.Round(minor))
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Amount
+		err   error
+	}{
+		{"0", 0, nil},
+		{"123", 12300, nil},
+		{"123.4", 12340, nil},
+		{"123.45", 12345, nil},
+		{"0.01", 1, nil},
+		{"-123.45", -12345, nil},
+		{"-0.01", -1, nil},
+		{"9999999999999.99", 999999999999999, nil},
+		{"-9999999999999.99", -999999999999999, nil},
+
+		// Больше Scale знаков после точки
+		{"1.234", 0, ErrInvalidAmount},
+		{"1.000", 0, ErrInvalidAmount},
+		{"-0.001", 0, ErrInvalidAmount},
+
+		// Больше maxWholeDigits знаков до точки и переполнение int64
+		{"10000000000000", 0, ErrInvalidAmount},
+		{"-10000000000000", 0, ErrInvalidAmount},
+		{"9223372036854775807", 0, ErrInvalidAmount},
+		{"99999999999999999999", 0, ErrInvalidAmount},
+
+		{"", 0, ErrInvalidAmount},
+		{"-", 0, ErrInvalidAmount},
+		{".", 0, ErrInvalidAmount},
+		{".5", 0, ErrInvalidAmount},
+		{"1.", 0, ErrInvalidAmount},
+		{"--1", 0, ErrInvalidAmount},
+		{"+1", 0, ErrInvalidAmount},
+		{" 1", 0, ErrInvalidAmount},
+		{"1,50", 0, ErrInvalidAmount},
+		{"1 000", 0, ErrInvalidAmount},
+		{"1e3", 0, ErrInvalidAmount},
+		{"NaN", 0, ErrInvalidAmount},
+		{"1.-5", 0, ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.input)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", test.input, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%q) = %d, want %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParsePositive(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{"0.01", nil},
+		{"0", ErrNonPositive},
+		{"0.00", ErrNonPositive},
+		{"-1", ErrNonPositive},
+		{"1.001", ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		if _, err := ParsePositive(test.input); !errors.Is(err, test.err) {
+			t.Errorf("ParsePositive(%q) error = %v, want %v", test.input, err, test.err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{-1, "-0.01"},
+		{12340, "123.40"},
+		{-12345, "-123.45"},
+	}
+	for _, test := range tests {
+		if got := test.amount.String(); got != test.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", test.amount, got, test.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Amount
+		fails bool
+	}{
+		{`"12.34"`, 1234, false},
+		{`12.34`, 1234, false},
+		{`-5`, -500, false},
+		{`null`, 0, false},
+		{`1.005`, 0, true},
+		{`1e2`, 0, true},
+		{`"abc"`, 0, true},
+	}
+	for _, test := range tests {
+		var got Amount
+		err := json.Unmarshal([]byte(test.input), &got)
+		if (err != nil) != test.fails {
+			t.Errorf("Unmarshal(%s) error = %v, want failure %v", test.input, err, test.fails)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", test.input, got, test.want)
+		}
+	}
+
+	data, err := json.Marshal(Amount(-12345))
+	if err != nil || string(data) != `"-123.45"` {
+		t.Errorf("Marshal(-12345) = %s, %v", data, err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  Amount
+	}{
+		{nil, 0},
+		{int64(12345), 12345},
+		{float64(12344.6), 12345},
+		{[]byte("12345"), 12345},
+		{"12345.0000", 12345},
+	}
+	for _, test := range tests {
+		var got Amount
+		if err := got.Scan(test.value); err != nil {
+			t.Errorf("Scan(%#v) error = %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Scan(%#v) = %d, want %d", test.value, got, test.want)
+		}
+	}
+	var amount Amount
+	if err := amount.Scan(true); err == nil {
+		t.Error("Scan(true) error = nil, want error")
+	}
+}
@@ -0,0 +1,142 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RateScale — число знаков после запятой, с которым курс хранится в базе
+const RateScale = 10
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate — курс обмена: сколько единиц одной валюты дают за единицу другой.
+// Хранится как NUMERIC, а вычисления ведутся в рациональных числах без округления
+type Rate struct {
+	rat *big.Rat
+}
+
+// ParseRate разбирает положительный курс в десятичной записи, например "92.5031"
+func ParseRate(s string) (Rate, error) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasFrac && (!isDigits(frac) || len(frac) > RateScale)) {
+		return Rate{}, ErrInvalidRate
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok || rat.Sign() <= 0 {
+		return Rate{}, ErrInvalidRate
+	}
+	return Rate{rat: rat}, nil
+}
+
+// OneRate — курс валюты к самой себе
+func OneRate() Rate {
+	return Rate{rat: big.NewRat(1, 1)}
+}
+
+func (r Rate) IsZero() bool {
+	return r.rat == nil || r.rat.Sign() == 0
+}
+
+// Inverse возвращает обратный курс
+func (r Rate) Inverse() Rate {
+	return Rate{rat: new(big.Rat).Inv(r.rat)}
+}
+
+// Mul возвращает кросс-курс: произведение двух курсов
+func (r Rate) Mul(other Rate) Rate {
+	return Rate{rat: new(big.Rat).Mul(r.rat, other.rat)}
+}
+
+// Convert пересчитывает сумму по курсу с округлением до копейки, половина — от нуля
+func (a Amount) Convert(r Rate) Amount {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(a)), r.rat)
+	return Amount(roundRat(product))
+}
+
+// Round округляет сумму до указанного числа знаков после запятой (не больше Scale)
+func (a Amount) Round(decimals int) Amount {
+	if decimals >= Scale {
+		return a
+	}
+	step := int64(1)
+	for i := decimals; i < Scale; i++ {
+		step *= 10
+	}
+	return Amount(roundRat(big.NewRat(int64(a), step)) * step)
+}
+
+func (r Rate) String() string {
+	if r.rat == nil {
+		return "0"
+	}
+	s := r.rat.FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(r.String())), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	rate, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	if r.rat == nil {
+		return nil, ErrInvalidRate
+	}
+	return r.rat.FloatString(RateScale), nil
+}
+
+func (r *Rate) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	default:
+		return fmt.Errorf("money: cannot scan rate from %T", value)
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("money: cannot scan rate %q", s)
+	}
+	r.rat = rat
+	return nil
+}
+
+// roundRat округляет рациональное число до целого, половина — от нуля
+func roundRat(value *big.Rat) int64 {
+	num := new(big.Int).Set(value.Num())
+	den := value.Denom()
+	negative := num.Sign() < 0
+	num.Abs(num)
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if negative {
+		quotient.Neg(quotient)
+	}
+	return quotient.Int64()
+}
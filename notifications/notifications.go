@@ -0,0 +1,169 @@
+package notifications
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/auth"
+	"project/database"
+	"project/logging"
+	"project/models"
+)
+
+var (
+	ErrUnknownKind      = errors.New("unknown notification kind")
+	ErrUnknownChannel   = errors.New("unknown notification channel")
+	ErrEmailNotVerified = errors.New("email is not verified")
+	ErrPrivateAddress   = errors.New("webhook address is not public")
+)
+
+// Kinds — виды уведомлений, для которых можно выбрать каналы
+var Kinds = []string{models.NotificationBudgetWarning, models.NotificationBudgetExceeded}
+
+// DefaultChannels — каналы вида уведомлений, для которого пользователь ничего не выбрал
+var DefaultChannels = []string{models.ChannelInbox, models.ChannelEmail}
+
+// Send доставляет уведомление пользователю по выбранным им каналам. Ошибка одного
+// канала не мешает остальным и только записывается в журнал
+func Send(userId uint, kind, title, body string) error {
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		return err
+	}
+	channels, err := channelsFor(userId, kind)
+	if err != nil {
+		return err
+	}
+	notification := models.Notification{Kind: kind, Title: title, Body: body, CreatedAt: time.Now()}
+	for _, channel := range channels {
+		notifier, ok := notifiers[channel]
+		if !ok {
+			continue
+		}
+		if err := notifier.Notify(user, notification); err != nil {
+			logging.Logger.Error("Failed to deliver notification", zap.Uint("user_id", userId),
+				zap.String("kind", kind), zap.String("channel", channel), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Preferences возвращает каналы каждого вида уведомлений с учётом каналов по умолчанию
+func Preferences(userId uint) (map[string][]string, error) {
+	var stored []models.NotificationPreference
+	if err := database.DB.Where("user_id = ?", userId).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	preferences := make(map[string][]string, len(Kinds))
+	for _, kind := range Kinds {
+		preferences[kind] = DefaultChannels
+	}
+	for _, preference := range stored {
+		preferences[preference.Kind] = splitChannels(preference.Channels)
+	}
+	return preferences, nil
+}
+
+// SetPreference задаёт каналы вида уведомлений; пустой список отключает его
+func SetPreference(userId uint, kind string, channels []string) error {
+	if !slices.Contains(Kinds, kind) {
+		return ErrUnknownKind
+	}
+	unique := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if !IsValidChannel(channel) {
+			return ErrUnknownChannel
+		}
+		if !slices.Contains(unique, channel) {
+			unique = append(unique, channel)
+		}
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channels"}),
+	}).Create(&models.NotificationPreference{
+		UserID:   userId,
+		Kind:     kind,
+		Channels: strings.Join(unique, ","),
+	}).Error
+}
+
+// Inbox возвращает последние уведомления из входящих пользователя
+func Inbox(userId uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := database.DB.Where("user_id = ?", userId)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.Notification
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkRead отмечает уведомление прочитанным; без notificationId — все уведомления.
+// Возвращает число изменённых уведомлений
+func MarkRead(userId uint, notificationId *uint) (int64, error) {
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId)
+	if notificationId != nil {
+		query = query.Where("id = ?", *notificationId)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// SetWebhook задаёт адрес вебхука и выпускает новый ключ подписи
+func SetWebhook(userId uint, url string) (models.NotificationWebhook, string, error) {
+	secret, err := auth.RandomToken()
+	if err != nil {
+		return models.NotificationWebhook{}, "", err
+	}
+	webhook := models.NotificationWebhook{UserID: userId, URL: url, Secret: secret, CreatedAt: time.Now()}
+	err = database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "secret", "created_at"}),
+	}).Create(&webhook).Error
+	return webhook, secret, err
+}
+
+// DeleteWebhook удаляет вебхук; возвращает false, если его не было
+func DeleteWebhook(userId uint) (bool, error) {
+	result := database.DB.Where("user_id = ?", userId).Delete(&models.NotificationWebhook{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteUserData удаляет уведомления и настройки уведомлений пользователя
+func DeleteUserData(tx *gorm.DB, userId uint) error {
+	for _, model := range []interface{}{
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.NotificationWebhook{},
+	} {
+		if err := tx.Where("user_id = ?", userId).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func channelsFor(userId uint, kind string) ([]string, error) {
+	var preference models.NotificationPreference
+	err := database.DB.Where("user_id = ? AND kind = ?", userId, kind).First(&preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultChannels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return splitChannels(preference.Channels), nil
+}
+
+func splitChannels(channels string) []string {
+	if channels == "" {
+		return []string{}
+	}
+	return strings.Split(channels, ",")
+}
@@ -0,0 +1,165 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+	"project/config"
+	"project/database"
+	"project/mail"
+	"project/models"
+)
+
+// Notifier доставляет уведомление пользователю по одному каналу
+type Notifier interface {
+	Channel() string
+	Notify(user models.User, notification models.Notification) error
+}
+
+// notifiers — доступные каналы доставки
+var notifiers = map[string]Notifier{}
+
+// Register добавляет канал доставки; канал с тем же именем заменяется
+func Register(notifier Notifier) {
+	notifiers[notifier.Channel()] = notifier
+}
+
+func init() {
+	Register(InboxNotifier{})
+	Register(EmailNotifier{})
+	Register(WebhookNotifier{})
+}
+
+// IsValidChannel сообщает, зарегистрирован ли канал доставки
+func IsValidChannel(channel string) bool {
+	_, ok := notifiers[channel]
+	return ok
+}
+
+// InboxNotifier сохраняет уведомление во входящих пользователя
+type InboxNotifier struct{}
+
+func (InboxNotifier) Channel() string {
+	return models.ChannelInbox
+}
+
+func (InboxNotifier) Notify(user models.User, notification models.Notification) error {
+	notification.UserID = user.ID
+	return database.DB.Create(&notification).Error
+}
+
+// EmailNotifier отправляет уведомление письмом на подтверждённый адрес
+type EmailNotifier struct{}
+
+func (EmailNotifier) Channel() string {
+	return models.ChannelEmail
+}
+
+func (EmailNotifier) Notify(user models.User, notification models.Notification) error {
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return mail.Send(mail.Message{
+		To:      user.Email,
+		Subject: notification.Title,
+		Body:    notification.Body,
+	})
+}
+
+// WebhookNotifier отправляет уведомление POST-запросом с телом JSON на адрес
+// пользователя. Тело подписывается ключом вебхука, подпись передаётся
+// в заголовке X-Signature в виде "sha256=<hex>"
+type WebhookNotifier struct{}
+
+func (WebhookNotifier) Channel() string {
+	return models.ChannelWebhook
+}
+
+func (WebhookNotifier) Notify(user models.User, notification models.Notification) error {
+	var webhook models.NotificationWebhook
+	if err := database.DB.Where("user_id = ?", user.ID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_id":    user.ID,
+		"kind":       notification.Kind,
+		"title":      notification.Title,
+		"body":       notification.Body,
+		"created_at": notification.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(payload)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := webhookClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookClient — общий клиент вебхуков: соединения переиспользуются между
+// доставками, а не остаются открытыми после каждой
+var webhookClient = sync.OnceValue(func() *http.Client {
+	cfg := config.GetConfig().Notifications
+	dialer := &net.Dialer{Timeout: cfg.WebhookTimeout}
+	if !cfg.AllowPrivateWebhooks {
+		dialer.Control = rejectPrivateAddress
+	}
+	return &http.Client{
+		Timeout:   cfg.WebhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, IdleConnTimeout: 90 * time.Second},
+	}
+})
+
+// sharedAddressSpace — адреса операторского NAT (RFC 6598)
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// rejectPrivateAddress запрещает вебхукам обращаться к адресам внутренней сети.
+// Проверяется адрес, к которому действительно идёт подключение, уже после разрешения имени.
+// Кроме частных сетей отклоняются loopback, link-local, multicast, неуказанный
+// и широковещательный адреса, а также адреса операторского NAT
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return ErrPrivateAddress
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package notifications
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRejectPrivateAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		private bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", false},
+		{"100.63.255.255:80", false},
+		{"100.128.0.0:80", false},
+
+		{"127.0.0.1:80", true},
+		{"[::1]:80", true},
+		{"10.1.2.3:80", true},
+		{"172.16.0.1:80", true},
+		{"192.168.1.1:80", true},
+		{"[fd00::1]:80", true},
+		{"169.254.169.254:80", true},
+		{"[fe80::1%eth0]:80", true},
+		{"100.64.0.1:80", true},
+		{"100.127.255.254:80", true},
+		{"224.0.0.1:80", true},
+		{"[ff02::1]:80", true},
+		{"0.0.0.0:80", true},
+		{"[::]:80", true},
+		{"255.255.255.255:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"[::ffff:100.64.0.1]:80", true},
+	}
+	for _, test := range tests {
+		err := rejectPrivateAddress("tcp", test.address, nil)
+		if errors.Is(err, ErrPrivateAddress) != test.private {
+			t.Errorf("rejectPrivateAddress(%s) = %v, want private %v", test.address, err, test.private)
+		}
+	}
+	if err := rejectPrivateAddress("tcp", "no-port", nil); err == nil {
+		t.Error("rejectPrivateAddress(no-port) error = nil, want error")
+	}
+}
@@ -0,0 +1,213 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"project/database"
+	"project/models"
+)
+
+// Время, за которое пользователь должен вернуться от провайдера
+const stateExpiration = 10 * time.Minute
+
+// Алгоритмы подписи ID-токенов, которые мы готовы принимать; HS* и none исключены
+var supportedAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var ErrInvalidState = errors.New("invalid or expired oidc state")
+
+// IDTokenClaims — проверенные данные пользователя из ID-токена
+type IDTokenClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// BeginLogin сохраняет state, nonce и PKCE verifier и возвращает адрес авторизации у провайдера
+func BeginLogin(ctx context.Context, providerName string) (authURL string, state string, err error) {
+	provider, err := GetProvider(ctx, providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err = randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	// Заодно удаляем состояния брошенных входов
+	now := time.Now()
+	if err := database.DB.Where("expires_at <= ?", now).Delete(&models.OIDCState{}).Error; err != nil {
+		return "", "", err
+	}
+	err = database.DB.Create(&models.OIDCState{
+		State:        state,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(stateExpiration),
+	}).Error
+	if err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	scopes := provider.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", provider.config.ClientID)
+	params.Set("redirect_uri", provider.config.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.discovery.AuthorizationEndpoint + separator + params.Encode(), state, nil
+}
+
+// CompleteLogin погашает state, обменивает код авторизации на токены и проверяет ID-токен
+func CompleteLogin(ctx context.Context, providerName, state, code string) (*IDTokenClaims, error) {
+	provider, err := GetProvider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored models.OIDCState
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ? AND provider = ?", state, providerName).First(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidState
+			}
+			return err
+		}
+		return tx.Delete(&stored).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidState
+	}
+
+	rawIDToken, err := provider.exchange(ctx, code, stored.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	return provider.verifyIDToken(ctx, rawIDToken, stored.Nonce)
+}
+
+func (p *Provider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc token endpoint: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	algs := supportedAlgs
+	if len(p.discovery.SigningAlgs) > 0 {
+		algs = nil
+		for _, alg := range p.discovery.SigningAlgs {
+			if slices.Contains(supportedAlgs, alg) {
+				algs = append(algs, alg)
+			}
+		}
+	}
+
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	}, jwt.WithValidMethods(algs),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt())
+	if err != nil {
+		return nil, fmt.Errorf("oidc id token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("oidc id token: nonce mismatch")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, errors.New("oidc id token: unexpected authorized party")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc id token: missing subject")
+	}
+	return claims, nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,268 @@
+package recurring
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"project/budgets"
+	"project/config"
+	"project/database"
+	"project/expenses"
+	"project/households"
+	"project/logging"
+	"project/models"
+	"project/money"
+)
+
+var (
+	ErrNotOccurrence    = errors.New("date is not an occurrence of the rule")
+	ErrAlreadyGenerated = errors.New("occurrence has already been generated")
+)
+
+// UpcomingOccurrence — предстоящее повторение с учётом пропуска или изменения
+type UpcomingOccurrence struct {
+	Date       string       `json:"date"`
+	Name       string       `json:"name"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"`
+	CategoryID uint         `json:"category_id"`
+	Skip       bool         `json:"skip"`
+	Modified   bool         `json:"modified"`
+}
+
+// materializeBatch ограничивает число повторений, которые обрабатываются за один
+// вызов Materialize, чтобы правило с давней датой начала не создавало тысячи
+// расходов в одной транзакции и в одном HTTP-запросе
+const materializeBatch = 100
+
+// StartScheduler запускает фоновое создание наступивших повторений
+func StartScheduler() {
+	go func() {
+		materializeDue()
+		ticker := time.NewTicker(config.GetConfig().Recurring.CheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			materializeDue()
+		}
+	}()
+}
+
+func materializeDue() {
+	// Сегодняшняя дата зависит от часового пояса владельца правила; завтрашняя
+	// дата в UTC не раньше неё ни в одном часовом поясе
+	tomorrow := expenses.Today(time.Now(), time.UTC).AddDate(0, 0, 1)
+	var ruleIds []uint
+	err := database.DB.Model(&models.RecurringExpense{}).
+		Where("next_date IS NOT NULL AND next_date <= ?", tomorrow).Pluck("id", &ruleIds).Error
+	if err != nil {
+		logging.Logger.Error("Failed to find due recurring expenses", zap.Error(err))
+		return
+	}
+	for _, ruleId := range ruleIds {
+		total := 0
+		for pending := true; pending; {
+			var created int
+			created, pending, err = Materialize(ruleId, time.Now())
+			if err != nil {
+				logging.Logger.Error("Failed to create recurring expenses", zap.Uint("recurring_id", ruleId), zap.Error(err))
+				break
+			}
+			total += created
+		}
+		if total > 0 {
+			logging.Logger.Info("Recurring expenses created", zap.Uint("recurring_id", ruleId), zap.Int("expenses", total))
+		}
+	}
+}
+
+// Materialize создаёт повторения правила, наступившие к сегодняшнему дню владельца,
+// но не больше materializeBatch за вызов. Возвращает число созданных расходов и то,
+// остались ли наступившие повторения. Правило блокируется до конца транзакции,
+// а занятое другим экземпляром приложения пропускается; уникальный индекс расходов
+// не даёт создать одно повторение дважды даже после сбоя
+func Materialize(ruleId uint, now time.Time) (int, bool, error) {
+	var created []models.Expense
+	var today time.Time
+	reassigned, pending := false, false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var rule models.RecurringExpense
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).First(&rule, ruleId).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		var owner models.User
+		if err := tx.Select("timezone").First(&owner, rule.UserID).Error; err != nil {
+			return err
+		}
+		loc, err := time.LoadLocation(owner.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		today = expenses.Today(now, loc)
+		if rule.NextDate == nil || rule.NextDate.After(today) {
+			return nil
+		}
+		// Расходы домохозяйства создаются только от имени его участника. Если автор
+		// правила покинул домохозяйство, правило переходит к владельцу
+		if rule.HouseholdID != nil {
+			if _, err := households.Authorize(*rule.HouseholdID, rule.UserID); err != nil {
+				if !errors.Is(err, households.ErrNotMember) {
+					return err
+				}
+				reassigned = true
+				return tx.Model(&rule).Update("user_id",
+					gorm.Expr("(SELECT user_id FROM household_members WHERE household_members.household_id = ? AND role = ? ORDER BY created_at LIMIT 1)",
+						*rule.HouseholdID, models.HouseholdRoleOwner)).Error
+			}
+		}
+
+		exceptions, err := loadExceptions(tx, rule.ID)
+		if err != nil {
+			return err
+		}
+		for processed := 0; rule.NextDate != nil && !rule.NextDate.After(today); processed++ {
+			if processed == materializeBatch {
+				pending = true
+				break
+			}
+			date := Occurrence(rule, rule.NextIndex)
+			exception, hasException := exceptions[date]
+			if !hasException || !exception.Skip {
+				expense := newExpense(rule, date, exception)
+				result := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "recurring_id"}, {Name: "occurrence_date"}},
+					DoNothing: true,
+				}).Create(&expense)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected > 0 {
+					created = append(created, expense)
+				}
+			}
+			advance(&rule)
+		}
+
+		// Исключения для созданных повторений больше не нужны
+		consumed := tx.Where("recurring_id = ?", rule.ID)
+		if rule.NextDate != nil {
+			consumed = consumed.Where("date < ?", *rule.NextDate)
+		}
+		if err := consumed.Delete(&models.RecurringException{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&rule).Select("next_index", "next_date").Updates(&rule).Error
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if reassigned {
+		logging.Logger.Info("Recurring expense reassigned to household owner", zap.Uint("recurring_id", ruleId))
+		return Materialize(ruleId, now)
+	}
+
+	// Все расходы правила относятся к одной категории, а состояние бюджета зависит
+	// только от суммы за период, поэтому достаточно проверить самый поздний
+	if len(created) > 0 {
+		expense := created[len(created)-1]
+		if err := budgets.CheckAlerts(expense, now); err != nil {
+			logging.Logger.Error("Failed to check budget alerts", zap.Uint("expense_id", expense.ID), zap.Error(err))
+		}
+	}
+	return len(created), pending, nil
+}
+
+// Upcoming возвращает ближайшие несозданные повторения правила
+func Upcoming(rule models.RecurringExpense, count int) ([]UpcomingOccurrence, error) {
+	exceptions, err := loadExceptions(database.DB, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+	upcoming := make([]UpcomingOccurrence, 0, count)
+	for index := rule.NextIndex; len(upcoming) < count && rule.NextDate != nil; index++ {
+		date := Occurrence(rule, index)
+		if rule.EndDate != nil && date.After(*rule.EndDate) {
+			break
+		}
+		exception, hasException := exceptions[date]
+		expense := newExpense(rule, date, exception)
+		upcoming = append(upcoming, UpcomingOccurrence{
+			Date:       date.Format("2006-01-02"),
+			Name:       expense.Name,
+			Amount:     expense.Amount,
+			Currency:   expense.Currency,
+			CategoryID: expense.CategoryID,
+			Skip:       exception.Skip,
+			Modified:   hasException && !exception.Skip,
+		})
+	}
+	return upcoming, nil
+}
+
+// SetException пропускает или изменяет одно ещё не созданное повторение
+func SetException(rule models.RecurringExpense, exception models.RecurringException) error {
+	index, ok := IndexOf(rule, exception.Date)
+	if !ok {
+		return ErrNotOccurrence
+	}
+	if index < rule.NextIndex {
+		return ErrAlreadyGenerated
+	}
+	exception.RecurringID = rule.ID
+	return database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&exception).Error
+}
+
+// DeleteException отменяет пропуск или изменение повторения; возвращает false, если его не было
+func DeleteException(ruleId uint, date time.Time) (bool, error) {
+	result := database.DB.Where("recurring_id = ? AND date = ?", ruleId, date).Delete(&models.RecurringException{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ResetExceptions удаляет пропуски и изменения повторений, например после смены расписания
+func ResetExceptions(tx *gorm.DB, ruleIds interface{}) error {
+	return tx.Where("recurring_id IN (?)", ruleIds).Delete(&models.RecurringException{}).Error
+}
+
+func loadExceptions(tx *gorm.DB, ruleId uint) (map[time.Time]models.RecurringException, error) {
+	var list []models.RecurringException
+	if err := tx.Where("recurring_id = ?", ruleId).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	exceptions := make(map[time.Time]models.RecurringException, len(list))
+	for _, exception := range list {
+		date := exception.Date
+		exceptions[time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)] = exception
+	}
+	return exceptions, nil
+}
+
+// newExpense строит расход повторения с учётом изменений из исключения
+func newExpense(rule models.RecurringExpense, date time.Time, exception models.RecurringException) models.Expense {
+	expense := models.Expense{
+		Name:           rule.Name,
+		UserID:         rule.UserID,
+		HouseholdID:    rule.HouseholdID,
+		CategoryID:     rule.CategoryID,
+		Amount:         rule.Amount,
+		Currency:       rule.Currency,
+		Date:           date,
+		RecurringID:    &rule.ID,
+		OccurrenceDate: &date,
+	}
+	if exception.Name != "" {
+		expense.Name = exception.Name
+	}
+	if exception.Amount != nil {
+		expense.Amount = *exception.Amount
+	}
+	if exception.CategoryID != nil {
+		expense.CategoryID = *exception.CategoryID
+	}
+	return expense
+}
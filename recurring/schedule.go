@@ -0,0 +1,102 @@
+package recurring
+
+import (
+	"time"
+
+	"project/models"
+)
+
+// IsValidFrequency сообщает, поддерживается ли частота повторения
+func IsValidFrequency(frequency string) bool {
+	switch frequency {
+	case models.FrequencyDaily, models.FrequencyWeekly, models.FrequencyMonthly, models.FrequencyYearly:
+		return true
+	}
+	return false
+}
+
+// Occurrence возвращает дату повторения с номером index. Даты считаются от StartDate,
+// а не от предыдущего повторения, поэтому расход 31-го числа после февраля снова
+// приходится на 31-е
+func Occurrence(rule models.RecurringExpense, index int) time.Time {
+	start := time.Date(rule.StartDate.Year(), rule.StartDate.Month(), rule.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	steps := index * rule.Interval
+	switch rule.Frequency {
+	case models.FrequencyWeekly:
+		return start.AddDate(0, 0, 7*steps)
+	case models.FrequencyMonthly:
+		return addMonths(start, steps)
+	case models.FrequencyYearly:
+		return addMonths(start, 12*steps)
+	}
+	return start.AddDate(0, 0, steps)
+}
+
+// Reset делает следующим первое повторение не раньше from
+func Reset(rule *models.RecurringExpense, from time.Time) {
+	setNext(rule, indexFrom(*rule, from))
+}
+
+// IndexOf возвращает номер повторения, которое приходится на дату; false — такого нет
+func IndexOf(rule models.RecurringExpense, date time.Time) (int, bool) {
+	index := indexFrom(rule, date)
+	if !Occurrence(rule, index).Equal(date) || rule.EndDate != nil && date.After(*rule.EndDate) {
+		return 0, false
+	}
+	return index, true
+}
+
+// indexFrom возвращает номер первого повторения не раньше date. Номер вычисляется
+// по разнице дат, а не перебором, поэтому далёкая дата не замедляет запрос
+func indexFrom(rule models.RecurringExpense, date time.Time) int {
+	start := Occurrence(rule, 0)
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if !date.After(start) {
+		return 0
+	}
+
+	var index int
+	switch rule.Frequency {
+	case models.FrequencyMonthly, models.FrequencyYearly:
+		// Повторение с номером n приходится на месяц start + n шагов, поэтому оценка
+		// по разнице месяцев не больше искомого номера и отличается от него не более чем на 1
+		step := max(rule.Interval, 1)
+		if rule.Frequency == models.FrequencyYearly {
+			step *= 12
+		}
+		months := (date.Year()-start.Year())*12 + int(date.Month()-start.Month())
+		index = months / step
+	default:
+		step := max(rule.Interval, 1)
+		if rule.Frequency == models.FrequencyWeekly {
+			step *= 7
+		}
+		days := int((date.Unix() - start.Unix()) / (24 * 60 * 60))
+		index = (days + step - 1) / step
+	}
+	for Occurrence(rule, index).Before(date) {
+		index++
+	}
+	return index
+}
+
+func advance(rule *models.RecurringExpense) {
+	setNext(rule, rule.NextIndex+1)
+}
+
+func setNext(rule *models.RecurringExpense, index int) {
+	rule.NextIndex = index
+	next := Occurrence(*rule, index)
+	if rule.EndDate != nil && next.After(*rule.EndDate) {
+		rule.NextDate = nil
+		return
+	}
+	rule.NextDate = &next
+}
+
+// addMonths прибавляет месяцы, не переходя в следующий месяц: 31 января + 1 месяц — 29 февраля
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return time.Date(first.Year(), first.Month(), min(date.Day(), lastDay), 0, 0, 0, 0, time.UTC)
+}
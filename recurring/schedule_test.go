@@ -0,0 +1,133 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		date   time.Time
+		months int
+		want   time.Time
+	}{
+		{date(2025, 1, 15), 1, date(2025, 2, 15)},
+		{date(2025, 1, 31), 1, date(2025, 2, 28)},
+		{date(2024, 1, 31), 1, date(2024, 2, 29)},
+		{date(2025, 1, 31), 2, date(2025, 3, 31)},
+		{date(2025, 3, 31), 1, date(2025, 4, 30)},
+		{date(2025, 12, 31), 2, date(2026, 2, 28)},
+		{date(2024, 2, 29), 12, date(2025, 2, 28)},
+		{date(2024, 2, 29), 48, date(2028, 2, 29)},
+		{date(2025, 3, 31), -1, date(2025, 2, 28)},
+	}
+	for _, test := range tests {
+		if got := addMonths(test.date, test.months); !got.Equal(test.want) {
+			t.Errorf("addMonths(%s, %d) = %s, want %s",
+				test.date.Format("2006-01-02"), test.months, got.Format("2006-01-02"), test.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestOccurrence(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  models.RecurringExpense
+		index int
+		want  time.Time
+	}{
+		{"daily", models.RecurringExpense{Frequency: models.FrequencyDaily, Interval: 1, StartDate: date(2025, 2, 27)}, 2, date(2025, 3, 1)},
+		{"daily leap", models.RecurringExpense{Frequency: models.FrequencyDaily, Interval: 1, StartDate: date(2024, 2, 28)}, 1, date(2024, 2, 29)},
+		{"every 3 days", models.RecurringExpense{Frequency: models.FrequencyDaily, Interval: 3, StartDate: date(2025, 1, 1)}, 2, date(2025, 1, 7)},
+		{"weekly", models.RecurringExpense{Frequency: models.FrequencyWeekly, Interval: 2, StartDate: date(2025, 1, 1)}, 3, date(2025, 2, 12)},
+		{"monthly end of january", models.RecurringExpense{Frequency: models.FrequencyMonthly, Interval: 1, StartDate: date(2025, 1, 31)}, 1, date(2025, 2, 28)},
+		{"monthly end of january leap", models.RecurringExpense{Frequency: models.FrequencyMonthly, Interval: 1, StartDate: date(2024, 1, 31)}, 1, date(2024, 2, 29)},
+		// Дата считается от StartDate, поэтому после февраля снова 31-е
+		{"monthly back to 31st", models.RecurringExpense{Frequency: models.FrequencyMonthly, Interval: 1, StartDate: date(2025, 1, 31)}, 2, date(2025, 3, 31)},
+		{"quarterly", models.RecurringExpense{Frequency: models.FrequencyMonthly, Interval: 3, StartDate: date(2025, 11, 30)}, 1, date(2026, 2, 28)},
+		{"yearly leap day", models.RecurringExpense{Frequency: models.FrequencyYearly, Interval: 1, StartDate: date(2024, 2, 29)}, 1, date(2025, 2, 28)},
+		{"yearly leap day again", models.RecurringExpense{Frequency: models.FrequencyYearly, Interval: 1, StartDate: date(2024, 2, 29)}, 4, date(2028, 2, 29)},
+		{"time of day ignored", models.RecurringExpense{Frequency: models.FrequencyDaily, Interval: 1, StartDate: time.Date(2025, 1, 1, 23, 30, 0, 0, time.UTC)}, 0, date(2025, 1, 1)},
+	}
+	for _, test := range tests {
+		if got := Occurrence(test.rule, test.index); !got.Equal(test.want) {
+			t.Errorf("%s: Occurrence(%d) = %s, want %s", test.name, test.index, got.Format("2006-01-02"), test.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestIndexFrom(t *testing.T) {
+	rules := []models.RecurringExpense{
+		{Frequency: models.FrequencyDaily, Interval: 1, StartDate: date(2024, 2, 27)},
+		{Frequency: models.FrequencyDaily, Interval: 5, StartDate: date(2024, 2, 27)},
+		{Frequency: models.FrequencyWeekly, Interval: 1, StartDate: date(2024, 1, 3)},
+		{Frequency: models.FrequencyWeekly, Interval: 3, StartDate: date(2024, 1, 3)},
+		{Frequency: models.FrequencyMonthly, Interval: 1, StartDate: date(2024, 1, 31)},
+		{Frequency: models.FrequencyMonthly, Interval: 2, StartDate: date(2023, 12, 30)},
+		{Frequency: models.FrequencyYearly, Interval: 1, StartDate: date(2024, 2, 29)},
+	}
+	for _, rule := range rules {
+		// Сравнение с перебором на всех датах за несколько лет
+		index := 0
+		for day := rule.StartDate.AddDate(0, 0, -3); day.Before(date(2029, 1, 1)); day = day.AddDate(0, 0, 1) {
+			for Occurrence(rule, index).Before(day) {
+				index++
+			}
+			if got := indexFrom(rule, day); got != index {
+				t.Fatalf("%s/%d from %s: indexFrom = %d, want %d",
+					rule.Frequency, rule.Interval, rule.StartDate.Format("2006-01-02"), got, index)
+			}
+		}
+	}
+
+	// Далёкая дата считается без перебора
+	rule := models.RecurringExpense{Frequency: models.FrequencyDaily, Interval: 1, StartDate: date(2025, 1, 1)}
+	far := date(9999, 12, 31)
+	if got := Occurrence(rule, indexFrom(rule, far)); !got.Equal(far) {
+		t.Errorf("Occurrence(indexFrom(%s)) = %s", far.Format("2006-01-02"), got.Format("2006-01-02"))
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	end := date(2025, 4, 30)
+	rule := models.RecurringExpense{Frequency: models.FrequencyMonthly, Interval: 1, StartDate: date(2025, 1, 31), EndDate: &end}
+	tests := []struct {
+		date  time.Time
+		index int
+		ok    bool
+	}{
+		{date(2025, 1, 31), 0, true},
+		{date(2025, 2, 28), 1, true},
+		{date(2025, 3, 31), 2, true},
+		{date(2025, 4, 30), 3, true},
+		{date(2025, 3, 30), 0, false},
+		{date(2025, 1, 30), 0, false},
+		{date(2025, 5, 31), 0, false},
+	}
+	for _, test := range tests {
+		index, ok := IndexOf(rule, test.date)
+		if index != test.index || ok != test.ok {
+			t.Errorf("IndexOf(%s) = %d, %v, want %d, %v", test.date.Format("2006-01-02"), index, ok, test.index, test.ok)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	end := date(2025, 3, 15)
+	rule := models.RecurringExpense{Frequency: models.FrequencyWeekly, Interval: 1, StartDate: date(2025, 1, 1), EndDate: &end}
+
+	Reset(&rule, date(2025, 1, 2))
+	if rule.NextIndex != 1 || rule.NextDate == nil || !rule.NextDate.Equal(date(2025, 1, 8)) {
+		t.Errorf("Reset(2025-01-02) = %d, %v", rule.NextIndex, rule.NextDate)
+	}
+	Reset(&rule, date(2025, 3, 13))
+	if rule.NextDate != nil {
+		t.Errorf("Reset after EndDate: NextDate = %s, want nil", rule.NextDate.Format("2006-01-02"))
+	}
+}
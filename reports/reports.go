@@ -0,0 +1,100 @@
+package reports
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"project/currency"
+	"project/models"
+	"project/money"
+)
+
+// CategoryBreakdown — разбивка расходов по категориям с итогом в валюте отчёта
+type CategoryBreakdown struct {
+	Total      money.Amount        `json:"total"`
+	Count      int64               `json:"count"`
+	Categories []models.SumExpense `json:"categories"`
+}
+
+// ByCategory группирует расходы запроса expenses по категориям и пересчитывает
+// суммы в валюту target. Суммы и количество считаются одним запросом с группировкой
+// по категории, валюте и дню, чтобы пересчитать каждую группу по курсу своей даты.
+// Если задан categories, в разбивку попадают и категории из этой выборки без расходов
+func ByCategory(expenses *gorm.DB, categories func(*gorm.DB) *gorm.DB, target string) (CategoryBreakdown, error) {
+	subquery := expenses.Select("expenses.id, expenses.category_id, expenses.currency, expenses.date, expenses.amount")
+	query := expenses.Session(&gorm.Session{NewDB: true}).Model(&models.Category{}).
+		Select("categories.id AS category_id, categories.name AS category, " +
+			"COALESCE(expenses.currency, '') AS currency, DATE(expenses.date) AS day, " +
+			"COALESCE(SUM(expenses.amount), 0) AS amount, COUNT(expenses.id) AS count")
+	if categories != nil {
+		visible := expenses.Session(&gorm.Session{NewDB: true}).Model(&models.Category{}).
+			Select("categories.id").Scopes(categories)
+		query = query.Joins("LEFT JOIN (?) AS expenses ON expenses.category_id = categories.id", subquery).
+			Where("(expenses.id IS NOT NULL OR categories.id IN (?))", visible)
+	} else {
+		query = query.Joins("JOIN (?) AS expenses ON expenses.category_id = categories.id", subquery)
+	}
+
+	var groups []struct {
+		CategoryID uint
+		Category   string
+		Currency   string
+		Day        *time.Time
+		Amount     money.Amount
+		Count      int64
+	}
+	err := query.Group("categories.id, categories.name, expenses.currency, DATE(expenses.date)").
+		Scan(&groups).Error
+	if err != nil {
+		return CategoryBreakdown{}, err
+	}
+
+	converter := currency.NewConverter(target)
+	byCategory := make(map[uint]*models.SumExpense)
+	var breakdown CategoryBreakdown
+	for _, group := range groups {
+		row, ok := byCategory[group.CategoryID]
+		if !ok {
+			row = &models.SumExpense{CategoryID: group.CategoryID, Category: group.Category}
+			byCategory[group.CategoryID] = row
+		}
+		if group.Count == 0 {
+			continue
+		}
+		converted, err := converter.Convert(group.Amount, group.Currency, *group.Day)
+		if err != nil {
+			return CategoryBreakdown{}, err
+		}
+		row.Sum += converted
+		row.Count += group.Count
+	}
+
+	breakdown.Categories = make([]models.SumExpense, 0, len(byCategory))
+	for _, row := range byCategory {
+		row.Sum = currency.Round(row.Sum, target)
+		breakdown.Total += row.Sum
+		breakdown.Count += row.Count
+		breakdown.Categories = append(breakdown.Categories, *row)
+	}
+	for i := range breakdown.Categories {
+		breakdown.Categories[i].Share = percent(breakdown.Categories[i].Sum, breakdown.Total)
+	}
+	sort.Slice(breakdown.Categories, func(i, j int) bool {
+		a, b := breakdown.Categories[i], breakdown.Categories[j]
+		if a.Sum != b.Sum {
+			return a.Sum > b.Sum
+		}
+		return a.CategoryID < b.CategoryID
+	})
+	return breakdown, nil
+}
+
+// percent возвращает долю части в итоге в процентах с точностью до сотых
+func percent(part, total money.Amount) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part.Minor())*10000/float64(total.Minor())) / 100
+}
@@ -0,0 +1,184 @@
+package reports
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"project/currency"
+	"project/money"
+)
+
+// Интервалы временного ряда
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+	BucketYear  = "year"
+)
+
+// MaxBuckets ограничивает длину ряда, чтобы дневной ряд за много лет не строился целиком
+const MaxBuckets = 1000
+
+var (
+	ErrInvalidBucket  = errors.New("invalid bucket")
+	ErrTooManyBuckets = errors.New("too many buckets")
+)
+
+// TimeseriesOptions — параметры временного ряда
+type TimeseriesOptions struct {
+	Bucket    string
+	WeekStart time.Weekday
+	// From — первый день ряда; nil — день самого раннего расхода. To — последний день
+	From *time.Time
+	To   time.Time
+	// ByCategory добавляет к ряду разбивку каждого интервала по категориям
+	ByCategory bool
+}
+
+// Bucket — интервал ряда: первый и последний день включительно и сумма расходов
+type Bucket struct {
+	Start string       `json:"start"`
+	End   string       `json:"end"`
+	Sum   money.Amount `json:"sum"`
+}
+
+// Series — суммы расходов одной категории по интервалам ряда
+type Series struct {
+	CategoryID uint           `json:"category_id"`
+	Category   string         `json:"category"`
+	Sums       []money.Amount `json:"sums"`
+}
+
+// TimeSeries — ряд сумм расходов; интервалы без расходов заполнены нулями
+type TimeSeries struct {
+	Buckets []Bucket `json:"buckets"`
+	Series  []Series `json:"series,omitempty"`
+}
+
+// IsValidBucket сообщает, поддерживается ли интервал
+func IsValidBucket(bucket string) bool {
+	switch bucket {
+	case BucketDay, BucketWeek, BucketMonth, BucketYear:
+		return true
+	}
+	return false
+}
+
+// Timeseries суммирует расходы запроса expenses по интервалам в валюте target.
+// Как и в ByCategory, расходы группируются по валюте и дню, пересчитываются по курсу
+// своей даты и только затем раскладываются по интервалам
+func Timeseries(expenses *gorm.DB, opts TimeseriesOptions, target string) (TimeSeries, error) {
+	if !IsValidBucket(opts.Bucket) {
+		return TimeSeries{}, ErrInvalidBucket
+	}
+
+	columns, group := "expenses.currency AS currency, DATE(expenses.date) AS day, SUM(expenses.amount) AS amount",
+		"expenses.currency, DATE(expenses.date)"
+	if opts.ByCategory {
+		columns = "expenses.category_id AS category_id, categories.name AS category, " + columns
+		group = "expenses.category_id, categories.name, " + group
+		expenses = expenses.Joins("JOIN categories ON categories.id = expenses.category_id")
+	}
+	var groups []struct {
+		CategoryID uint
+		Category   string
+		Currency   string
+		Day        time.Time
+		Amount     money.Amount
+	}
+	if err := expenses.Select(columns).Group(group).Scan(&groups).Error; err != nil {
+		return TimeSeries{}, err
+	}
+
+	from := opts.To
+	if opts.From != nil {
+		from = *opts.From
+	}
+	for _, row := range groups {
+		if opts.From == nil && row.Day.Before(from) {
+			from = row.Day
+		}
+	}
+
+	// Начала интервалов и их номера в ряду
+	var starts []time.Time
+	index := make(map[time.Time]int)
+	for start := BucketStart(from, opts.Bucket, opts.WeekStart); !start.After(opts.To); start = NextBucket(start, opts.Bucket) {
+		if len(starts) == MaxBuckets {
+			return TimeSeries{}, ErrTooManyBuckets
+		}
+		index[start] = len(starts)
+		starts = append(starts, start)
+	}
+
+	converter := currency.NewConverter(target)
+	categories := make(map[uint]*Series)
+	for _, row := range groups {
+		i, ok := index[BucketStart(row.Day, opts.Bucket, opts.WeekStart)]
+		if !ok {
+			continue
+		}
+		series, ok := categories[row.CategoryID]
+		if !ok {
+			series = &Series{CategoryID: row.CategoryID, Category: row.Category, Sums: make([]money.Amount, len(starts))}
+			categories[row.CategoryID] = series
+		}
+		converted, err := converter.Convert(row.Amount, row.Currency, row.Day)
+		if err != nil {
+			return TimeSeries{}, err
+		}
+		series.Sums[i] += converted
+	}
+
+	// Итог интервала — сумма округлённых сумм категорий, чтобы разбивка сходилась с итогом
+	var result TimeSeries
+	result.Buckets = make([]Bucket, len(starts))
+	for i, start := range starts {
+		result.Buckets[i] = Bucket{
+			Start: start.Format("2006-01-02"),
+			End:   NextBucket(start, opts.Bucket).AddDate(0, 0, -1).Format("2006-01-02"),
+		}
+	}
+	for _, series := range categories {
+		for i := range series.Sums {
+			series.Sums[i] = currency.Round(series.Sums[i], target)
+			result.Buckets[i].Sum += series.Sums[i]
+		}
+		if opts.ByCategory {
+			result.Series = append(result.Series, *series)
+		}
+	}
+	sort.Slice(result.Series, func(i, j int) bool {
+		return result.Series[i].CategoryID < result.Series[j].CategoryID
+	})
+	return result, nil
+}
+
+// BucketStart возвращает первый день интервала, в который попадает день
+func BucketStart(day time.Time, bucket string, weekStart time.Weekday) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case BucketWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) - int(weekStart) + 7) % 7))
+	case BucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	case BucketYear:
+		return time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// NextBucket возвращает первый день следующего интервала
+func NextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	case BucketYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
@@ -2,21 +2,121 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v3"
+	"project/auth"
 	"project/controllers"
+	"project/middleware"
+	"project/models"
 )
 
 func SetupRoutes(app *fiber.App) {
 	app.Get("/", controllers.Hello)
-	app.Post("/api/register", controllers.Register)
-	app.Post("/api/login", controllers.Login)
-	app.Get("/api/user", controllers.User)
+	app.Get("/.well-known/jwks.json", controllers.JWKS)
+	app.Post("/api/register", controllers.Register, middleware.RateLimit())
+	app.Post("/api/login", controllers.Login, middleware.RateLimit())
+	app.Post("/api/login/2fa", controllers.LoginTwoFactor, middleware.RateLimit())
 	app.Post("/api/logout", controllers.Logout)
-	app.Get("/api/categories", controllers.GetCategories)
-	app.Post("/api/categories", controllers.AddCategoryByUser)
-	app.Get("/api/expenses", controllers.GetExpenses)
-	app.Post("/api/expenses", controllers.AddExpenseByUser)
-	app.Delete("/api/expenses/:id", controllers.DeleteExpense)
-	app.Put("/api/expenses/:id", controllers.UpdateExpense)
-	app.Get("/api/expenses/category/:category_id", controllers.GetSumExpensesByCategoryId)
-	app.Get("/api/expenses/sum", controllers.GetSumExpenses)
+	app.Post("/api/token/refresh", controllers.RefreshToken)
+	app.Post("/api/password/forgot", controllers.ForgotPassword, middleware.RateLimit())
+	app.Post("/api/password/reset", controllers.ResetPassword, middleware.RateLimit())
+	app.Post("/api/email/verify", controllers.VerifyEmail)
+	app.Get("/api/oidc/:provider/login", controllers.OIDCLogin, middleware.RateLimit())
+	app.Get("/api/oidc/:provider/callback", controllers.OIDCCallback, middleware.RateLimit())
+
+	// Маршруты, доступные и по персональным токенам доступа с нужной областью
+	scoped := app.Group("/api")
+	scoped.Get("/categories", controllers.GetCategories, middleware.Protected(auth.ScopeCategoriesRead))
+	scoped.Post("/categories", controllers.AddCategoryByUser, middleware.Protected(auth.ScopeCategoriesWrite))
+	scoped.Get("/expenses", controllers.GetExpenses, middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Post("/expenses", controllers.AddExpenseByUser,
+		middleware.Protected(auth.ScopeExpensesWrite), middleware.RequireVerifiedEmail())
+	scoped.Post("/expenses/import", controllers.ImportExpenses,
+		middleware.Protected(auth.ScopeExpensesWrite), middleware.RequireVerifiedEmail())
+	scoped.Post("/expenses/import/statement", controllers.ImportStatement,
+		middleware.Protected(auth.ScopeExpensesWrite), middleware.RequireVerifiedEmail())
+	scoped.Delete("/expenses/:id", controllers.DeleteExpense, middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Put("/expenses/:id", controllers.UpdateExpense, middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Get("/expenses/category/:category_id", controllers.GetSumExpensesByCategoryId,
+		middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Get("/expenses/sum", controllers.GetSumExpenses, middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Get("/expenses/:id/split", controllers.GetExpenseSplit, middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Put("/expenses/:id/split", controllers.SetExpenseSplit, middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Delete("/expenses/:id/split", controllers.DeleteExpenseSplit, middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Get("/recurring", controllers.GetRecurringExpenses, middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Post("/recurring", controllers.CreateRecurringExpense,
+		middleware.Protected(auth.ScopeExpensesWrite), middleware.RequireVerifiedEmail())
+	scoped.Put("/recurring/:id", controllers.UpdateRecurringExpense, middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Delete("/recurring/:id", controllers.DeleteRecurringExpense, middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Get("/recurring/:id/occurrences", controllers.GetRecurringOccurrences,
+		middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Put("/recurring/:id/occurrences/:date", controllers.SetRecurringOccurrence,
+		middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Delete("/recurring/:id/occurrences/:date", controllers.DeleteRecurringOccurrence,
+		middleware.Protected(auth.ScopeExpensesWrite))
+	scoped.Get("/reports/by-category", controllers.GetCategoryReport, middleware.Protected(auth.ScopeExpensesRead))
+	scoped.Get("/reports/timeseries", controllers.GetTimeseriesReport, middleware.Protected(auth.ScopeExpensesRead))
+
+	// Публичные и доступные по токенам маршруты должны быть зарегистрированы выше:
+	// middleware группы срабатывает для всех оставшихся запросов с префиксом /api
+	api := app.Group("/api", middleware.Protected())
+	api.Get("/user", controllers.User)
+	api.Put("/user", controllers.UpdateUser)
+	api.Delete("/user", controllers.DeleteUser)
+	api.Post("/user/password", controllers.ChangePassword)
+	api.Post("/user/restore", controllers.RestoreUser)
+	api.Post("/logout/all", controllers.LogoutAll)
+	api.Post("/email/resend", controllers.ResendVerificationEmail)
+	api.Post("/2fa/setup", controllers.SetupTwoFactor)
+	api.Post("/2fa/confirm", controllers.ConfirmTwoFactor)
+	api.Post("/2fa/disable", controllers.DisableTwoFactor)
+	api.Post("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+	api.Get("/user/identities", controllers.GetIdentities)
+	api.Get("/sessions", controllers.GetSessions)
+	api.Delete("/sessions", controllers.DeleteOtherSessions)
+	api.Delete("/sessions/:id", controllers.DeleteSession)
+	api.Get("/tokens", controllers.GetAPITokens)
+	api.Post("/tokens", controllers.CreateAPIToken)
+	api.Delete("/tokens/:id", controllers.DeleteAPIToken)
+	api.Get("/rates", controllers.GetRates)
+	api.Get("/households", controllers.GetHouseholds)
+	api.Post("/households", controllers.CreateHousehold)
+	api.Post("/households/join", controllers.AcceptHouseholdInvitation)
+	api.Get("/households/:id", controllers.GetHousehold)
+	api.Put("/households/:id", controllers.UpdateHousehold)
+	api.Delete("/households/:id", controllers.DeleteHousehold)
+	api.Get("/households/:id/invitations", controllers.GetHouseholdInvitations)
+	api.Post("/households/:id/invitations", controllers.CreateHouseholdInvitation)
+	api.Delete("/households/:id/invitations/:invitation_id", controllers.DeleteHouseholdInvitation)
+	api.Put("/households/:id/members/:user_id", controllers.UpdateHouseholdMember)
+	api.Delete("/households/:id/members/:user_id", controllers.DeleteHouseholdMember)
+	api.Get("/households/:id/balances", controllers.GetHouseholdBalances)
+	api.Get("/households/:id/balances/simplified", controllers.GetSimplifiedDebts)
+	api.Get("/households/:id/settlements", controllers.GetSettlements)
+	api.Post("/households/:id/settlements", controllers.CreateSettlement)
+	api.Delete("/households/:id/settlements/:settlement_id", controllers.DeleteSettlement)
+	api.Get("/budgets", controllers.GetBudgets)
+	api.Post("/budgets", controllers.CreateBudget)
+	api.Get("/budgets/status", controllers.GetBudgetStatus)
+	api.Put("/budgets/:id", controllers.UpdateBudget)
+	api.Delete("/budgets/:id", controllers.DeleteBudget)
+	api.Get("/notifications", controllers.GetNotifications)
+	api.Post("/notifications/read", controllers.MarkAllNotificationsRead)
+	api.Get("/notifications/preferences", controllers.GetNotificationPreferences)
+	api.Put("/notifications/preferences", controllers.UpdateNotificationPreference)
+	api.Put("/notifications/webhook", controllers.SetNotificationWebhook)
+	api.Delete("/notifications/webhook", controllers.DeleteNotificationWebhook)
+	api.Post("/notifications/:id/read", controllers.MarkNotificationRead)
+
+	admin := api.Group("/admin", middleware.RequireRole(models.RoleAdmin))
+	admin.Get("/users", controllers.AdminGetUsers)
+	admin.Post("/users/:id/disable", controllers.AdminDisableUser)
+	admin.Post("/users/:id/enable", controllers.AdminEnableUser)
+	admin.Put("/users/:id/role", controllers.AdminSetUserRole)
+	admin.Get("/categories", controllers.AdminGetCategories)
+	admin.Post("/categories", controllers.AdminAddCategory)
+	admin.Put("/categories/:id", controllers.AdminUpdateCategory)
+	admin.Delete("/categories/:id", controllers.AdminDeleteCategory)
+	admin.Get("/stats", controllers.AdminGetStats)
+	admin.Post("/rates", controllers.AdminImportRates)
+	admin.Post("/rates/sync", controllers.AdminSyncRates)
+	admin.Delete("/rates/:base/:quote/:date", controllers.AdminDeleteRate)
 }
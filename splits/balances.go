@@ -0,0 +1,283 @@
+package splits
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+	"time"
+
+	"project/currency"
+	"project/database"
+	"project/models"
+	"project/money"
+)
+
+var ErrInvalidSettlement = errors.New("invalid settlement")
+
+// Debt — сумма, которую один участник должен другому
+type Debt struct {
+	FromUserID uint         `json:"from_user_id"`
+	ToUserID   uint         `json:"to_user_id"`
+	Amount     money.Amount `json:"amount"`
+}
+
+// Balances возвращает попарные долги участников домохозяйства: доли в расходах,
+// оплаченных другими участниками, за вычетом зарегистрированных погашений.
+// Суммы пересчитываются в валюту домохозяйства по курсу на дату расхода или погашения
+func Balances(householdId uint, target string) ([]Debt, error) {
+	var owed []movement
+	err := database.DB.Table("expense_shares").
+		Select("expense_shares.user_id AS from_user_id, expenses.user_id AS to_user_id, "+
+			"expenses.currency AS currency, DATE(expenses.date) AS day, SUM(expense_shares.amount) AS amount").
+		Joins("JOIN expenses ON expenses.id = expense_shares.expense_id").
+		Where("expenses.household_id = ? AND expense_shares.user_id <> expenses.user_id", householdId).
+		Group("expense_shares.user_id, expenses.user_id, expenses.currency, DATE(expenses.date)").
+		Scan(&owed).Error
+	if err != nil {
+		return nil, err
+	}
+	var settled []movement
+	err = database.DB.Model(&models.Settlement{}).
+		Select("from_user_id, to_user_id, currency, DATE(date) AS day, SUM(amount) AS amount").
+		Where("household_id = ?", householdId).
+		Group("from_user_id, to_user_id, currency, DATE(date)").
+		Scan(&settled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	converter := currency.NewConverter(target)
+	for _, list := range [][]movement{owed, settled} {
+		for i := range list {
+			list[i].Amount, err = converter.Convert(list[i].Amount, list[i].Currency, list[i].Day)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Ключ — упорядоченная пара; положительное значение означает, что первый должен второму
+	pairs := make(map[[2]uint]int64)
+	add := func(from, to uint, cents int64) {
+		if from < to {
+			pairs[[2]uint{from, to}] += cents
+		} else {
+			pairs[[2]uint{to, from}] -= cents
+		}
+	}
+	for _, debt := range owed {
+		add(debt.FromUserID, debt.ToUserID, debt.Amount.Minor())
+	}
+	for _, payment := range settled {
+		add(payment.FromUserID, payment.ToUserID, -payment.Amount.Minor())
+	}
+
+	debts := make([]Debt, 0, len(pairs))
+	for pair, cents := range pairs {
+		switch {
+		case cents > 0:
+			debts = append(debts, Debt{FromUserID: pair[0], ToUserID: pair[1], Amount: currency.Round(money.FromMinor(cents), target)})
+		case cents < 0:
+			debts = append(debts, Debt{FromUserID: pair[1], ToUserID: pair[0], Amount: currency.Round(money.FromMinor(-cents), target)})
+		}
+	}
+	sortDebts(debts)
+	return debts, nil
+}
+
+// movement — сумма долга или погашения между двумя участниками в валюте операции
+type movement struct {
+	FromUserID uint
+	ToUserID   uint
+	Currency   string
+	Day        time.Time
+	Amount     money.Amount
+}
+
+// NetBalances сводит попарные долги к итогу по каждому участнику:
+// положительный итог — участнику должны, отрицательный — должен он
+func NetBalances(debts []Debt) map[uint]money.Amount {
+	cents := netCents(debts)
+	net := make(map[uint]money.Amount, len(cents))
+	for userId, balance := range cents {
+		net[userId] = money.FromMinor(balance)
+	}
+	return net
+}
+
+// exactSimplifyLimit — наибольшее число участников с ненулевым итогом, для которого
+// Simplify ищет наименьшее число переводов перебором подмножеств
+const exactSimplifyLimit = 16
+
+// Simplify заменяет попарные долги переводами с теми же итогами по участникам.
+// Наименьшее число переводов равно числу участников с ненулевым итогом минус
+// наибольшее число групп, в которых итоги взаимно гасятся, поэтому участники
+// разбиваются на такие группы, а внутри группы наибольший должник каждый раз платит
+// наибольшему кредитору. Если участников больше exactSimplifyLimit, группы не
+// ищутся и переводов может получиться больше наименьшего, но не больше, чем
+// участников с ненулевым итогом, минус один
+func Simplify(debts []Debt) []Debt {
+	var balances []balance
+	for userId, cents := range netCents(debts) {
+		if cents != 0 {
+			balances = append(balances, balance{userId, cents})
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].userId < balances[j].userId })
+
+	if len(balances) > exactSimplifyLimit {
+		return settleGroup(balances)
+	}
+	var transfers []Debt
+	for _, group := range zeroSumGroups(balances) {
+		transfers = append(transfers, settleGroup(group)...)
+	}
+	return transfers
+}
+
+// balance — итог участника: положительный — участнику должны, отрицательный — должен он
+type balance struct {
+	userId uint
+	cents  int64
+}
+
+// zeroSumGroups разбивает итоги на наибольшее число групп с нулевой суммой.
+// groups[mask] — наибольшее число таких групп среди участников из mask
+func zeroSumGroups(balances []balance) [][]balance {
+	full := 1<<len(balances) - 1
+	sums := make([]int64, full+1)
+	groups := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sums[mask] = sums[mask&(mask-1)] + balances[low].cents
+		for rest := mask; rest != 0; rest &= rest - 1 {
+			groups[mask] = max(groups[mask], groups[mask&^(rest&-rest)])
+		}
+		if sums[mask] == 0 {
+			groups[mask]++
+		}
+	}
+
+	// Участники убираются по одному с сохранением наибольшего числа групп;
+	// между соседними подмножествами с нулевой суммой оказывается одна группа
+	var result [][]balance
+	var group []balance
+	for mask := full; mask != 0; {
+		bonus := 0
+		if sums[mask] == 0 {
+			bonus = 1
+		}
+		for rest := mask; rest != 0; rest &= rest - 1 {
+			next := mask &^ (rest & -rest)
+			if groups[next]+bonus == groups[mask] {
+				group = append(group, balances[bits.TrailingZeros(uint(rest))])
+				mask = next
+				break
+			}
+		}
+		if sums[mask] == 0 {
+			result = append(result, group)
+			group = nil
+		}
+	}
+	return result
+}
+
+// settleGroup гасит итоги группы: наибольший должник каждый раз платит наибольшему
+// кредитору, поэтому переводов не больше, чем участников в группе, минус один
+func settleGroup(balances []balance) []Debt {
+	var debtors, creditors []balance
+	for _, item := range balances {
+		switch {
+		case item.cents < 0:
+			debtors = append(debtors, balance{item.userId, -item.cents})
+		case item.cents > 0:
+			creditors = append(creditors, item)
+		}
+	}
+	byAmount := func(list []balance) func(i, j int) bool {
+		return func(i, j int) bool {
+			if list[i].cents != list[j].cents {
+				return list[i].cents > list[j].cents
+			}
+			return list[i].userId < list[j].userId
+		}
+	}
+
+	var transfers []Debt
+	for len(debtors) > 0 && len(creditors) > 0 {
+		sort.Slice(debtors, byAmount(debtors))
+		sort.Slice(creditors, byAmount(creditors))
+		amount := min(debtors[0].cents, creditors[0].cents)
+		transfers = append(transfers, Debt{
+			FromUserID: debtors[0].userId,
+			ToUserID:   creditors[0].userId,
+			Amount:     money.FromMinor(amount),
+		})
+		debtors[0].cents -= amount
+		creditors[0].cents -= amount
+		if debtors[0].cents == 0 {
+			debtors = debtors[1:]
+		}
+		if creditors[0].cents == 0 {
+			creditors = creditors[1:]
+		}
+	}
+	return transfers
+}
+
+// RecordSettlement регистрирует перевод между участниками домохозяйства
+func RecordSettlement(settlement *models.Settlement) error {
+	if settlement.FromUserID == settlement.ToUserID || settlement.Amount <= 0 {
+		return ErrInvalidSettlement
+	}
+	var members int64
+	err := database.DB.Model(&models.HouseholdMember{}).
+		Where("household_id = ? AND user_id IN ?", settlement.HouseholdID,
+			[]uint{settlement.FromUserID, settlement.ToUserID}).
+		Count(&members).Error
+	if err != nil {
+		return err
+	}
+	if members != 2 {
+		return ErrNotMember
+	}
+	if settlement.Date.IsZero() {
+		settlement.Date = time.Now()
+	}
+	return database.DB.Create(settlement).Error
+}
+
+// Settlements возвращает погашения домохозяйства, начиная с последних
+func Settlements(householdId uint) ([]models.Settlement, error) {
+	var settlements []models.Settlement
+	err := database.DB.Where("household_id = ?", householdId).
+		Order("date DESC, id DESC").Find(&settlements).Error
+	return settlements, err
+}
+
+// DeleteSettlement удаляет ошибочно зарегистрированное погашение
+func DeleteSettlement(householdId, settlementId uint) (bool, error) {
+	result := database.DB.Where("id = ? AND household_id = ?", settlementId, householdId).
+		Delete(&models.Settlement{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func netCents(debts []Debt) map[uint]int64 {
+	net := make(map[uint]int64)
+	for _, debt := range debts {
+		cents := debt.Amount.Minor()
+		net[debt.FromUserID] -= cents
+		net[debt.ToUserID] += cents
+	}
+	return net
+}
+
+func sortDebts(debts []Debt) {
+	sort.Slice(debts, func(i, j int) bool {
+		if debts[i].FromUserID != debts[j].FromUserID {
+			return debts[i].FromUserID < debts[j].FromUserID
+		}
+		return debts[i].ToUserID < debts[j].ToUserID
+	})
+}
@@ -0,0 +1,123 @@
+package splits
+
+import (
+	"testing"
+
+	"project/money"
+)
+
+// debtsFromNet строит долги через промежуточного участника hub, итог которого нулевой
+func debtsFromNet(net map[uint]int64) []Debt {
+	const hub = 1000
+	var debts []Debt
+	for userId, cents := range net {
+		switch {
+		case cents > 0:
+			debts = append(debts, Debt{FromUserID: hub, ToUserID: userId, Amount: money.FromMinor(cents)})
+		case cents < 0:
+			debts = append(debts, Debt{FromUserID: userId, ToUserID: hub, Amount: money.FromMinor(-cents)})
+		}
+	}
+	return debts
+}
+
+// checkSettles проверяет, что переводы дают те же итоги по участникам, а каждый
+// перевод идёт от должника к кредитору
+func checkSettles(t *testing.T, name string, debts, transfers []Debt) {
+	t.Helper()
+	want := netCents(debts)
+	got := netCents(transfers)
+	for userId, cents := range want {
+		if got[userId] != cents {
+			t.Errorf("%s: net of user %d = %d, want %d", name, userId, got[userId], cents)
+		}
+	}
+	for userId, cents := range got {
+		if cents != 0 && want[userId] != cents {
+			t.Errorf("%s: user %d has net %d without debts", name, userId, cents)
+		}
+	}
+	for _, transfer := range transfers {
+		if transfer.Amount <= 0 || transfer.FromUserID == transfer.ToUserID ||
+			want[transfer.FromUserID] >= 0 || want[transfer.ToUserID] <= 0 {
+			t.Errorf("%s: invalid transfer %+v", name, transfer)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name      string
+		net       map[uint]int64
+		transfers int
+	}{
+		{"empty", map[uint]int64{}, 0},
+		{"single debt", map[uint]int64{1: 500, 2: -500}, 1},
+		{"two independent pairs", map[uint]int64{1: 500, 2: -500, 3: 300, 4: -300}, 2},
+		{"pairs with different amounts", map[uint]int64{1: 500, 2: 400, 3: -400, 4: -500}, 2},
+		// Ни одна часть участников не гасит свои итоги сама, поэтому переводов n-1
+		{"no zero-sum subgroup", map[uint]int64{1: 500, 2: 500, 3: -400, 4: -600}, 3},
+		{"one creditor", map[uint]int64{1: 1000, 2: -300, 3: -300, 4: -400}, 3},
+		{"three pairs", map[uint]int64{1: 100, 2: -100, 3: 200, 4: -200, 5: 300, 6: -300}, 3},
+		{"pair and triple", map[uint]int64{1: 600, 2: 400, 3: -300, 4: -300, 5: -400}, 3},
+		// Наибольший должник платит наибольшему кредитору: так получилось бы четыре перевода
+		{"greedy would need four", map[uint]int64{1: 300, 2: 400, 3: -200, 4: -200, 5: -300}, 3},
+	}
+	for _, test := range tests {
+		debts := debtsFromNet(test.net)
+		transfers := Simplify(debts)
+		checkSettles(t, test.name, debts, transfers)
+		if len(transfers) != test.transfers {
+			t.Errorf("%s: %d transfers, want %d: %+v", test.name, len(transfers), test.transfers, transfers)
+		}
+	}
+
+	// Взаимные долги гасятся без переводов
+	mutual := []Debt{
+		{FromUserID: 1, ToUserID: 2, Amount: money.FromMinor(500)},
+		{FromUserID: 2, ToUserID: 1, Amount: money.FromMinor(500)},
+	}
+	if transfers := Simplify(mutual); len(transfers) != 0 {
+		t.Errorf("mutual debts: transfers = %+v, want none", transfers)
+	}
+}
+
+func TestZeroSumGroups(t *testing.T) {
+	balances := []balance{{1, 500}, {2, -500}, {3, 300}, {4, 200}, {5, -500}, {6, 100}, {7, -100}}
+	groups := zeroSumGroups(balances)
+	if len(groups) != 3 {
+		t.Fatalf("groups = %v, want 3 groups", groups)
+	}
+	seen := map[uint]bool{}
+	for _, group := range groups {
+		var sum int64
+		for _, item := range group {
+			if seen[item.userId] {
+				t.Errorf("user %d is in several groups", item.userId)
+			}
+			seen[item.userId] = true
+			sum += item.cents
+		}
+		if sum != 0 {
+			t.Errorf("group %v sums to %d", group, sum)
+		}
+	}
+	if len(seen) != len(balances) {
+		t.Errorf("groups cover %d users, want %d", len(seen), len(balances))
+	}
+}
+
+func TestSimplifyLargeGroup(t *testing.T) {
+	// Больше exactSimplifyLimit участников: группы не ищутся, но долги гасятся полностью
+	net := map[uint]int64{}
+	for userId := uint(1); userId <= exactSimplifyLimit+4; userId += 2 {
+		net[userId] = int64(userId) * 100
+		net[userId+1] = -int64(userId) * 100
+	}
+	debts := debtsFromNet(net)
+	transfers := Simplify(debts)
+	checkSettles(t, "large group", debts, transfers)
+	if len(transfers) > len(net)-1 {
+		t.Errorf("large group: %d transfers, want at most %d", len(transfers), len(net)-1)
+	}
+}
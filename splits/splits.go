@@ -0,0 +1,207 @@
+package splits
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"gorm.io/gorm"
+	"project/currency"
+	"project/database"
+	"project/models"
+	"project/money"
+)
+
+var (
+	ErrNotHouseholdExpense = errors.New("only household expenses can be split")
+	ErrInvalidSplit        = errors.New("invalid split")
+	ErrNotMember           = errors.New("participant is not a household member")
+	ErrExactSplit          = errors.New("exact shares do not match the expense amount")
+)
+
+// Share — доля участника в запросе на разделение расхода. Для способа equal
+// важен только UserID, для exact — Amount, для percent — Percent
+type Share struct {
+	UserID  uint
+	Amount  money.Amount
+	Percent float64
+}
+
+// Compute распределяет сумму расхода между участниками. Расчёт ведётся в разменных
+// единицах валюты; единицы, оставшиеся после округления, раздаются так, чтобы сумма
+// долей точно совпадала с суммой расхода
+func Compute(method string, amount money.Amount, code string, shares []Share) ([]models.ExpenseShare, error) {
+	step := currency.Step(code)
+	total := amount.Minor() / step
+	if len(shares) == 0 || total <= 0 {
+		return nil, ErrInvalidSplit
+	}
+	seen := make(map[uint]bool, len(shares))
+	for _, share := range shares {
+		if share.UserID == 0 || seen[share.UserID] {
+			return nil, ErrInvalidSplit
+		}
+		seen[share.UserID] = true
+	}
+
+	cents := make([]int64, len(shares))
+	switch method {
+	case models.SplitEqual:
+		count := int64(len(shares))
+		for i := range cents {
+			cents[i] = total / count
+			if int64(i) < total%count {
+				cents[i]++
+			}
+		}
+	case models.SplitExact:
+		var sum int64
+		for i, share := range shares {
+			cents[i] = share.Amount.Minor() / step
+			if cents[i] < 0 || cents[i]*step != share.Amount.Minor() {
+				return nil, ErrInvalidSplit
+			}
+			sum += cents[i]
+		}
+		if sum != total {
+			return nil, ErrExactSplit
+		}
+	case models.SplitPercent:
+		var percentSum float64
+		for _, share := range shares {
+			if share.Percent < 0 || math.IsNaN(share.Percent) {
+				return nil, ErrInvalidSplit
+			}
+			percentSum += share.Percent
+		}
+		if math.Abs(percentSum-100) > 1e-6 {
+			return nil, ErrInvalidSplit
+		}
+		// Метод наибольшего остатка: недостающие копейки получают доли
+		// с самой большой отброшенной дробной частью
+		var assigned int64
+		remainders := make([]float64, len(shares))
+		for i, share := range shares {
+			exact := float64(total) * share.Percent / 100
+			cents[i] = int64(math.Floor(exact))
+			remainders[i] = exact - float64(cents[i])
+			assigned += cents[i]
+		}
+		order := make([]int, len(shares))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return remainders[order[a]] > remainders[order[b]]
+		})
+		for i := 0; assigned < total; i++ {
+			cents[order[i%len(order)]]++
+			assigned++
+		}
+	default:
+		return nil, ErrInvalidSplit
+	}
+
+	result := make([]models.ExpenseShare, len(shares))
+	for i, share := range shares {
+		result[i] = models.ExpenseShare{UserID: share.UserID, Amount: money.FromMinor(cents[i] * step)}
+		if method == models.SplitPercent {
+			result[i].Percent = share.Percent
+		}
+	}
+	return result, nil
+}
+
+// Apply разделяет расход домохозяйства между его участниками, заменяя прежние доли
+func Apply(expense *models.Expense, method string, shares []Share) ([]models.ExpenseShare, error) {
+	if expense.HouseholdID == nil {
+		return nil, ErrNotHouseholdExpense
+	}
+	computed, err := Compute(method, expense.Amount, expense.Currency, shares)
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := make([]uint, len(computed))
+	for i, share := range computed {
+		userIds[i] = share.UserID
+	}
+	var members int64
+	err = database.DB.Model(&models.HouseholdMember{}).
+		Where("household_id = ? AND user_id IN ?", *expense.HouseholdID, userIds).
+		Count(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	if int(members) != len(userIds) {
+		return nil, ErrNotMember
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceShares(tx, expense, method, computed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return computed, nil
+}
+
+// Clear отменяет разделение расхода
+func Clear(expense *models.Expense) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceShares(tx, expense, "", nil)
+	})
+}
+
+// ForExpense возвращает доли участников в расходе
+func ForExpense(expenseId uint) ([]models.ExpenseShare, error) {
+	var shares []models.ExpenseShare
+	err := database.DB.Where("expense_id = ?", expenseId).Order("id").Find(&shares).Error
+	return shares, err
+}
+
+// Recalculate пересчитывает доли после изменения суммы расхода. Точные суммы
+// пересчитать нельзя, поэтому такой расход нужно сначала разделить заново
+func Recalculate(tx *gorm.DB, expense *models.Expense) error {
+	if expense.SplitMethod == "" {
+		return nil
+	}
+	if expense.SplitMethod == models.SplitExact {
+		return ErrExactSplit
+	}
+
+	var existing []models.ExpenseShare
+	if err := tx.Where("expense_id = ?", expense.ID).Order("id").Find(&existing).Error; err != nil {
+		return err
+	}
+	shares := make([]Share, len(existing))
+	for i, share := range existing {
+		shares[i] = Share{UserID: share.UserID, Percent: share.Percent}
+	}
+	computed, err := Compute(expense.SplitMethod, expense.Amount, expense.Currency, shares)
+	if err != nil {
+		return err
+	}
+	return replaceShares(tx, expense, expense.SplitMethod, computed)
+}
+
+// DeleteShares удаляет доли расходов перед удалением самих расходов
+func DeleteShares(tx *gorm.DB, expenseIds interface{}) error {
+	return tx.Where("expense_id IN (?)", expenseIds).Delete(&models.ExpenseShare{}).Error
+}
+
+func replaceShares(tx *gorm.DB, expense *models.Expense, method string, shares []models.ExpenseShare) error {
+	if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseShare{}).Error; err != nil {
+		return err
+	}
+	for i := range shares {
+		shares[i].ExpenseID = expense.ID
+	}
+	if len(shares) > 0 {
+		if err := tx.Create(&shares).Error; err != nil {
+			return err
+		}
+	}
+	expense.SplitMethod = method
+	return tx.Model(&models.Expense{}).Where("id = ?", expense.ID).Update("split_method", method).Error
+}
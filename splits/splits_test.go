@@ -0,0 +1,157 @@
+package splits
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"project/models"
+	"project/money"
+)
+
+func TestCompute(t *testing.T) {
+	third := 100.0 / 3
+	tests := []struct {
+		name     string
+		method   string
+		amount   money.Amount
+		currency string
+		shares   []Share
+		want     []money.Amount
+		err      error
+	}{
+		{
+			name: "equal", method: models.SplitEqual, amount: 10000, currency: "RUB",
+			shares: []Share{{UserID: 1}, {UserID: 2}, {UserID: 3}},
+			want:   []money.Amount{3334, 3333, 3333},
+		},
+		{
+			name: "equal two remainders", method: models.SplitEqual, amount: 1000, currency: "USD",
+			shares: []Share{{UserID: 1}, {UserID: 2}, {UserID: 3}, {UserID: 4}, {UserID: 5}, {UserID: 6}},
+			want:   []money.Amount{167, 167, 167, 167, 166, 166},
+		},
+		{
+			name: "equal less than one unit each", method: models.SplitEqual, amount: 2, currency: "RUB",
+			shares: []Share{{UserID: 1}, {UserID: 2}, {UserID: 3}},
+			want:   []money.Amount{1, 1, 0},
+		},
+		{
+			// Иена без дробных единиц: остаток раздаётся целыми иенами
+			name: "equal without minor units", method: models.SplitEqual, amount: 10000, currency: "JPY",
+			shares: []Share{{UserID: 1}, {UserID: 2}, {UserID: 3}},
+			want:   []money.Amount{3400, 3300, 3300},
+		},
+		{
+			name: "exact", method: models.SplitExact, amount: 10000, currency: "RUB",
+			shares: []Share{{UserID: 1, Amount: 2550}, {UserID: 2, Amount: 7450}, {UserID: 3, Amount: 0}},
+			want:   []money.Amount{2550, 7450, 0},
+		},
+		{
+			name: "exact sum mismatch", method: models.SplitExact, amount: 10000, currency: "RUB",
+			shares: []Share{{UserID: 1, Amount: 2550}, {UserID: 2, Amount: 7449}},
+			err:    ErrExactSplit,
+		},
+		{
+			name: "exact below currency unit", method: models.SplitExact, amount: 10000, currency: "JPY",
+			shares: []Share{{UserID: 1, Amount: 4950}, {UserID: 2, Amount: 5050}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "exact negative", method: models.SplitExact, amount: 10000, currency: "RUB",
+			shares: []Share{{UserID: 1, Amount: -100}, {UserID: 2, Amount: 10100}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "percent", method: models.SplitPercent, amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: 12.5}, {UserID: 2, Percent: 12.5}, {UserID: 3, Percent: 75}},
+			want:   []money.Amount{125, 125, 750},
+		},
+		{
+			// Наибольший остаток: обе доли отбрасывают по 0.5, копейку получает первая
+			name: "percent equal remainders", method: models.SplitPercent, amount: 10, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: 15}, {UserID: 2, Percent: 85}},
+			want:   []money.Amount{2, 8},
+		},
+		{
+			name: "percent largest remainder", method: models.SplitPercent, amount: 100, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: 10}, {UserID: 2, Percent: 45}, {UserID: 3, Percent: 45}},
+			want:   []money.Amount{10, 45, 45},
+		},
+		{
+			name: "percent thirds", method: models.SplitPercent, amount: 100, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: third}, {UserID: 2, Percent: third}, {UserID: 3, Percent: third}},
+			want:   []money.Amount{34, 33, 33},
+		},
+		{
+			name: "percent remainder to largest fraction", method: models.SplitPercent, amount: 100, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: 10.2}, {UserID: 2, Percent: 30.7}, {UserID: 3, Percent: 59.1}},
+			want:   []money.Amount{10, 31, 59},
+		},
+		{
+			name: "percent sum below 100", method: models.SplitPercent, amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: 50}, {UserID: 2, Percent: 49}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "percent negative", method: models.SplitPercent, amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: 110}, {UserID: 2, Percent: -10}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "percent NaN", method: models.SplitPercent, amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 1, Percent: math.NaN()}, {UserID: 2, Percent: 100}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "duplicate participant", method: models.SplitEqual, amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 1}, {UserID: 1}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "missing participant", method: models.SplitEqual, amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 0}, {UserID: 1}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "no participants", method: models.SplitEqual, amount: 1000, currency: "RUB",
+			err: ErrInvalidSplit,
+		},
+		{
+			name: "zero amount", method: models.SplitEqual, amount: 0, currency: "RUB",
+			shares: []Share{{UserID: 1}},
+			err:    ErrInvalidSplit,
+		},
+		{
+			name: "unknown method", method: "shares", amount: 1000, currency: "RUB",
+			shares: []Share{{UserID: 1}},
+			err:    ErrInvalidSplit,
+		},
+	}
+	for _, test := range tests {
+		got, err := Compute(test.method, test.amount, test.currency, test.shares)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Fatalf("%s: %d shares, want %d", test.name, len(got), len(test.want))
+		}
+		var sum money.Amount
+		for i, share := range got {
+			sum += share.Amount
+			if share.UserID != test.shares[i].UserID || share.Amount != test.want[i] {
+				t.Errorf("%s: share %d = user %d %s, want user %d %s", test.name, i,
+					share.UserID, share.Amount, test.shares[i].UserID, test.want[i])
+			}
+			if test.method == models.SplitPercent && share.Percent != test.shares[i].Percent {
+				t.Errorf("%s: share %d percent = %v, want %v", test.name, i, share.Percent, test.shares[i].Percent)
+			}
+		}
+		if sum != test.amount {
+			t.Errorf("%s: shares sum to %s, want %s", test.name, sum, test.amount)
+		}
+	}
+}